@@ -0,0 +1,113 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrCommitUnknown is returned by CommitContext when the context is canceled
+// while the COMMIT is in progress, or when the connection is lost during the COMMIT.
+// The transaction may or may not have been committed.
+//
+// See: https://github.com/golang/go/issues/28474
+var ErrCommitUnknown = errors.New("sql: commit outcome unknown")
+
+// commitState records the outcome of a COMMIT issued by CommitContext.
+type commitState struct {
+	done chan struct{}
+	err  error
+
+	// Used to run the query passed to ResolveCommit
+	killerPool StdSQLDB
+}
+
+// CommitContext commits the transaction.
+//
+// If the context is already canceled, the transaction is rolled back and the
+// context's error is returned. If the context is canceled while the COMMIT is in
+// progress, ErrCommitUnknown is returned immediately and the COMMIT is allowed
+// to finish in the background. ResolveCommit can then be used to determine
+// whether the transaction was committed.
+//
+// Callbacks registered using OnCommit are only called if the COMMIT succeeds,
+// even if CommitContext has already returned. If the connection is lost during
+// the COMMIT, callbacks registered using OnUnknown are called instead.
+func (tx *Tx) CommitContext(ctx context.Context) error {
+
+	if err := ctx.Err(); err != nil {
+		// The COMMIT was never sent
+		tx.Rollback()
+		return err
+	}
+
+	cs := &commitState{
+		done:       make(chan struct{}),
+		killerPool: tx.killerPool,
+	}
+	tx.lock.Lock()
+	tx.commit = cs
+	tx.lock.Unlock()
+
+	go func() {
+		cs.err = tx.Commit()
+		close(cs.done)
+	}()
+
+	select {
+	case <-cs.done:
+		if isConnErr(cs.err) {
+			return ErrCommitUnknown
+		}
+		return cs.err
+	case <-ctx.Done():
+		return ErrCommitUnknown
+	}
+}
+
+// ResolveCommit determines whether a transaction was committed after
+// CommitContext returned ErrCommitUnknown.
+//
+// It first waits for the COMMIT to finish (bounded by the context). If the outcome
+// is still unknown because the connection was lost, the query is run using the
+// KillerPool. The KillerPool is used because it is bound to the server that received
+// the COMMIT, even when the DB fails over to a new primary. The query acts as an
+// idempotency marker: it must return at least one row if (and only if) the
+// transaction was committed. For example, it can select a row that was written
+// inside the transaction. The query should be cheap since it delays KILL signals.
+func (tx *Tx) ResolveCommit(ctx context.Context, query string, args ...interface{}) (bool, error) {
+
+	tx.lock.Lock()
+	cs := tx.commit
+	tx.lock.Unlock()
+
+	if cs == nil {
+		return false, errors.New("sql: CommitContext was not called")
+	}
+
+	select {
+	case <-cs.done:
+		if cs.err == nil {
+			return true, nil
+		}
+		if !isConnErr(cs.err) {
+			return false, nil
+		}
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+
+	// The connection was lost during the COMMIT
+	if cs.killerPool == nil {
+		return false, errors.New("sql: no KillerPool to resolve the commit")
+	}
+	rows, err := cs.killerPool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	committed := rows.Next()
+	return committed, rows.Err()
+}
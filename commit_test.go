@@ -0,0 +1,112 @@
+package sql_test
+
+import (
+	"context"
+	stdSql "database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+// openFaultDB returns a DB whose main pool injects faults into the statements
+// recorded by rec. The KillerPool is backed by a separate Recorder.
+func openFaultDB(rec *sqltest.Recorder, faults ...sqltest.Fault) (*sql.DB, *sqltest.Recorder) {
+	killer := sqltest.NewRecorder()
+	fc := &sqltest.FaultConnector{Connector: rec, Faults: faults}
+	return &sql.DB{DB: stdSql.OpenDB(fc), KillerPool: stdSql.OpenDB(killer)}, killer
+}
+
+func TestCommitContext(t *testing.T) {
+	pool := sqltest.NewRecorder().DB()
+	defer pool.Close()
+
+	tx := beginTx(t, pool)
+	require.NoError(t, tx.CommitContext(context.Background()))
+
+	committed, err := tx.ResolveCommit(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+	assert.True(t, committed)
+}
+
+func TestCommitContextCanceled(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	pool := rec.DB()
+	defer pool.Close()
+
+	tx := beginTx(t, pool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The COMMIT is never sent
+	assert.Equal(t, context.Canceled, tx.CommitContext(ctx))
+
+	var queries []string
+	for _, s := range rec.Statements() {
+		queries = append(queries, s.Query)
+	}
+	assert.Contains(t, queries, "ROLLBACK")
+	assert.NotContains(t, queries, "COMMIT")
+}
+
+func TestCommitContextConnectionLost(t *testing.T) {
+	rec := sqltest.NewRecorder()
+
+	pool, killer := openFaultDB(rec, sqltest.Fault{Query: regexp.MustCompile(`^COMMIT$`), Always: true, Drop: true})
+	defer pool.Close()
+	killer.Expect(`^SELECT id FROM orders`).WillReturnRows([]string{"id"}, []interface{}{1})
+
+	var h hooks
+	tx := beginTx(t, pool)
+	h.register(tx)
+
+	assert.Equal(t, sql.ErrCommitUnknown, tx.CommitContext(context.Background()))
+	assert.Len(t, h.unknown, 1)
+	assert.Empty(t, h.rolledBack)
+
+	committed, err := tx.ResolveCommit(context.Background(), "SELECT id FROM orders WHERE token = ?", "abc")
+	require.NoError(t, err)
+	assert.True(t, committed)
+
+	// The query is run using the KillerPool
+	for _, s := range rec.Statements() {
+		assert.NotContains(t, s.Query, "orders")
+	}
+	assert.NotEmpty(t, killer.Statements())
+
+	killer.Expect(`^SELECT id FROM payments`).WillReturnRows([]string{"id"})
+	committed, err = tx.ResolveCommit(context.Background(), "SELECT id FROM payments WHERE token = ?", "abc")
+	require.NoError(t, err)
+	assert.False(t, committed)
+}
+
+func TestCommitContextTimeout(t *testing.T) {
+	rec := sqltest.NewRecorder()
+
+	pool, _ := openFaultDB(rec, sqltest.Fault{Query: regexp.MustCompile(`^COMMIT$`), Always: true, Latency: 100 * time.Millisecond})
+	defer pool.Close()
+
+	var h hooks
+	tx := beginTx(t, pool)
+	h.register(tx)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// CommitContext returns before the COMMIT has finished
+	start := time.Now()
+	assert.Equal(t, sql.ErrCommitUnknown, tx.CommitContext(ctx))
+	assert.True(t, time.Since(start) < 100*time.Millisecond)
+
+	// ResolveCommit waits for the COMMIT to finish
+	committed, err := tx.ResolveCommit(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+	assert.True(t, committed)
+	assert.Equal(t, 1, h.committed)
+}
@@ -5,7 +5,8 @@ package sql
 import (
 	"context"
 	stdSql "database/sql"
-	"time"
+	"runtime"
+	"sync/atomic"
 )
 
 // Conn represents a single database connection rather than a pool of database
@@ -18,10 +19,11 @@ import (
 // After a call to Close, all operations on the
 // connection fail with ErrConnDone.
 type Conn struct {
-	conn         *stdSql.Conn
-	killerPool   StdSQLDB
-	connectionID string
-	kto          time.Duration
+	session
+	conn *stdSql.Conn
+
+	closed  int32
+	tracked *trackedHandle
 }
 
 // Unleak will release the reference to the killerPool
@@ -29,6 +31,7 @@ type Conn struct {
 func (c *Conn) Unleak() {
 	c.killerPool = nil
 	c.connectionID = ""
+	c.tracked.markUnleaked()
 }
 
 // Begin starts a transaction. The default isolation level is dependent on the driver.
@@ -48,12 +51,25 @@ func (c *Conn) Begin(opts *stdSql.TxOptions) (*Tx, error) {
 // an error will be returned.
 func (c *Conn) BeginTx(ctx context.Context, opts *stdSql.TxOptions) (*Tx, error) {
 
-	tx, err := c.conn.BeginTx(ctx, opts)
+	var tx *stdSql.Tx
+	err := c.run(ctx, OpBegin, func(ctx context.Context) error {
+		var err error
+		tx, err = c.conn.BeginTx(ctx, opts)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &Tx{tx: tx, killerPool: c.killerPool, connectionID: c.connectionID}, nil
+	t := &Tx{session: c.session, tx: tx}
+	if m := c.db.txMonitor(); m != nil {
+		t.monitored = m.track(t)
+	}
+	if t.monitored != nil || (c.db != nil && c.db.Logger != nil) {
+		runtime.SetFinalizer(t, (*Tx).leaked)
+	}
+	c.db.trackTx(t)
+	return t, nil
 }
 
 // Close returns the connection to the connection pool.
@@ -62,6 +78,8 @@ func (c *Conn) BeginTx(ctx context.Context, opts *stdSql.TxOptions) (*Tx, error)
 // block until all other operations finish. It may be useful to first
 // cancel any used context and then call close directly after.
 func (c *Conn) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	c.tracked.markClosed()
 	err := c.conn.Close()
 	if err != nil {
 		return err
@@ -79,44 +97,9 @@ func (c *Conn) Exec(query string, args ...interface{}) (stdSql.Result, error) {
 // ExecContext executes a query without returning any rows.
 // The args are for any placeholder parameters in the query.
 func (c *Conn) ExecContext(ctx context.Context, query string, args ...interface{}) (stdSql.Result, error) {
-
-	// Create a context that is used to cancel ExecContext()
-	cancelCtx, cancelFunc := context.WithCancel(context.Background())
-	defer cancelFunc()
-
-	outChan := make(chan stdSql.Result)
-	errChan := make(chan error)
-	returnedChan := make(chan struct{}) // Used to indicate that this function has returned
-
-	defer func() {
-		returnedChan <- struct{}{}
-	}()
-
-	go func() {
-		select {
-		case <-ctx.Done():
-			// context has been canceled
-			kill(c.killerPool, c.connectionID, c.kto)
-			errChan <- ctx.Err()
-		case <-returnedChan:
-		}
-	}()
-
-	go func() {
-		res, err := c.conn.ExecContext(cancelCtx, query, args...)
-		if err != nil {
-			errChan <- err
-			return
-		}
-		outChan <- res
-	}()
-
-	select {
-	case err := <-errChan:
-		return nil, err
-	case out := <-outChan:
-		return out, nil
-	}
+	return c.runExec(ctx, &Call{Query: query, Args: args}, func(ctx context.Context, call *Call) (stdSql.Result, error) {
+		return c.conn.ExecContext(ctx, call.Query, call.Args...)
+	})
 }
 
 // Ping verifies a connection to the database is still alive.
@@ -149,13 +132,9 @@ func (c *Conn) Prepare(query string) (*Stmt, error) {
 // The provided context is used for the preparation of the statement, not for the
 // execution of the statement.
 func (c *Conn) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
-	// You can not cancel a Prepare.
-	// See: https://github.com/rocketlaunchr/mysql-go/issues/3
-	stmt, err := c.conn.PrepareContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	return &Stmt{stmt, c.killerPool, c.connectionID, c.kto}, nil
+	return c.runPrepare(ctx, &Call{Query: query}, func(ctx context.Context, call *Call) (*stdSql.Stmt, error) {
+		return c.conn.PrepareContext(ctx, call.Query)
+	})
 }
 
 // Query executes a query that returns rows, typically a SELECT.
@@ -167,17 +146,9 @@ func (c *Conn) Query(query string, args ...interface{}) (*Rows, error) {
 // QueryContext executes a query that returns rows, typically a SELECT.
 // The args are for any placeholder parameters in the query.
 func (c *Conn) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
-
-	// We can't use the same approach used in ExecContext because defer cancelFunc()
-	// cancels rows.Scan.
-	defer func() {
-		if ctx.Err() != nil {
-			kill(c.killerPool, c.connectionID, c.kto)
-		}
-	}()
-
-	rows, err := c.conn.QueryContext(ctx, query, args...)
-	return &Rows{ctx: ctx, rows: rows, killerPool: c.killerPool, connectionID: c.connectionID}, err
+	return c.runQuery(ctx, &Call{Query: query, Args: args}, func(ctx context.Context, call *Call) (*stdSql.Rows, error) {
+		return c.conn.QueryContext(ctx, call.Query, call.Args...)
+	})
 }
 
 // QueryRow executes a query that is expected to return at most one row.
@@ -197,14 +168,7 @@ func (c *Conn) QueryRow(query string, args ...interface{}) *Row {
 // Otherwise, the *Row's Scan scans the first selected row and discards
 // the rest.
 func (c *Conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
-
-	// Since sql.Row does not export err field, this is the best we can do:
-	defer func() {
-		if ctx.Err() != nil {
-			kill(c.killerPool, c.connectionID, c.kto)
-		}
-	}()
-
-	row := c.conn.QueryRowContext(ctx, query, args...)
-	return &Row{ctx: ctx, row: row, killerPool: c.killerPool, connectionID: c.connectionID}
+	return c.runQueryRow(ctx, &Call{Query: query, Args: args}, func(ctx context.Context, call *Call) (*stdSql.Rows, error) {
+		return c.conn.QueryContext(ctx, call.Query, call.Args...)
+	})
 }
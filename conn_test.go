@@ -1,167 +1,141 @@
-package sql
+package sql_test
 
 // Special thanks to @soniah for tests below.
 
 import (
 	"bytes"
 	"context"
-	"database/sql"
+	stdSql "database/sql"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
-	"sync"
 	"testing"
 	"text/tabwriter"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
-	"github.com/jmoiron/sqlx"
-	"github.com/ory/dockertest"
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // nolint:gochecknoglobals
-var dockerPool *dockertest.Pool // the connection to docker
+var server *sqltest.Server // the in-process stand-in for mysql
 // nolint:gochecknoglobals
-var systemdb *sql.DB // the connection to the mysql 'system' database
-// nolint:gochecknoglobals
-var sqlConfig *mysql.Config // the mysql container and config for connecting to other databases
-// nolint:gochecknoglobals
-var testMu *sync.Mutex // controls access to sqlConfig
+var systemdb *stdSql.DB // the connection to the server used to inspect the processlist
 
 func TestMain(m *testing.M) {
 	_ = mysql.SetLogger(log.New(ioutil.Discard, "", 0)) // silence mysql logger
-	testMu = &sync.Mutex{}
 
 	var err error
-	dockerPool, err = dockertest.NewPool("")
+	server, err = sqltest.NewServer()
 	if err != nil {
-		log.Fatalf("could not connect to docker: %s", err)
+		log.Fatalf("could not start server: %s", err)
 	}
-	dockerPool.MaxWait = time.Minute * 2
 
-	runOptions := dockertest.RunOptions{
-		Repository: "mysql",
-		Tag:        "5.6",
-		Env:        []string{"MYSQL_ROOT_PASSWORD=secret"},
-	}
-	mysqlContainer, err := dockerPool.RunWithOptions(&runOptions)
+	systemdb, err = stdSql.Open("mysql", server.DSN())
 	if err != nil {
-		log.Fatalf("could not start mysqlContainer: %s", err)
-	}
-
-	sqlConfig = &mysql.Config{
-		User:                 "root",
-		Passwd:               "secret",
-		Net:                  "tcp",
-		Addr:                 fmt.Sprintf("localhost:%s", mysqlContainer.GetPort("3306/tcp")),
-		DBName:               "mysql",
-		AllowNativePasswords: true,
+		log.Fatal(err)
 	}
-
-	if err = dockerPool.Retry(func() error {
-		systemdb, err = sql.Open("mysql", sqlConfig.FormatDSN())
-		if err != nil {
-			return err
-		}
-		return systemdb.Ping()
-	}); err != nil {
+	if err = systemdb.Ping(); err != nil {
 		log.Fatal(err)
 	}
 
 	code := m.Run()
 
 	// You can't defer this because os.Exit ignores defer
-	if err := dockerPool.Purge(mysqlContainer); err != nil {
-		log.Fatalf("Could not purge resource: %s", err)
+	systemdb.Close()
+	if err := server.Close(); err != nil {
+		log.Fatalf("Could not close server: %s", err)
 	}
 
 	os.Exit(code)
 }
 
 func TestCancel(t *testing.T) {
-	var err error
-	_, err = systemdb.Exec("create database TestCancel")
-	assert.NoError(t, err)
-
-	testMu.Lock()
-	testCancelConfig := sqlConfig
-	testMu.Unlock()
-	testCancelConfig.DBName = "TestCancel"
-	var dbStd *sql.DB
-	if err := dockerPool.Retry(func() error {
-		dbStd, err = sql.Open("mysql", testCancelConfig.FormatDSN())
-		if err != nil {
-			return err
-		}
-		return dbStd.Ping()
-	}); err != nil {
-		log.Fatal(err)
-	}
+	server.Handle(`^select benchmark`, sqltest.Response{Delay: time.Minute})
 
-	dbKiller, err := sql.Open("mysql", testCancelConfig.FormatDSN())
-	dbKiller.SetMaxOpenConns(1)
-	pool := &DB{DB: dbStd, KillerPool: dbKiller}
+	dbStd, err := stdSql.Open("mysql", server.DSN())
+	require.NoError(t, err)
+	defer dbStd.Close()
 
-	procs, err := helperFullProcessList(dbStd)
-	assert.NoError(t, err)
+	dbKiller, err := stdSql.Open("mysql", server.DSN())
+	require.NoError(t, err)
+	defer dbKiller.Close()
+	dbKiller.SetMaxOpenConns(1)
+	pool := &sql.DB{DB: dbStd, KillerPool: dbKiller}
 
-	filterDB := func(m mySQLProcInfo) bool { return m.DB == "TestCancel" }
 	filterState := func(m mySQLProcInfo) bool { return m.State == "executing" }
-	procs = procs.Filter(filterDB, filterState)
+	filterInfo := func(m mySQLProcInfo) bool { return m.Info != nil && *m.Info != "show full processlist" }
+
+	procs, err := helperFullProcessList(systemdb)
+	require.NoError(t, err)
+	procs = procs.Filter(filterState, filterInfo)
 	assert.Len(t, procs, 0)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
 	defer cancel()
 	conn, err := pool.Conn(ctx)
-	assert.NoError(t, err)
+	require.NoError(t, err)
 	defer conn.Close()
 
+	done := make(chan error, 1)
 	go func() {
-		_, err = conn.ExecContext(ctx, "select benchmark(9999999999, md5('I like traffic lights'))")
-		assert.Equal(t, context.DeadlineExceeded, err)
+		_, err := conn.ExecContext(ctx, "select benchmark(9999999999, md5('I like traffic lights'))")
+		done <- err
 	}()
 
 	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
 Loop:
 	for {
 		select {
 		case <-ticker.C:
-			procs, err := helperFullProcessList(dbStd)
-			assert.NoError(t, err)
-			procs = procs.Filter(filterDB, filterState)
-			assert.Len(t, procs, 1)
+			procs, err := helperFullProcessList(systemdb)
+			require.NoError(t, err)
+			procs = procs.Filter(filterState, filterInfo)
+			assert.Len(t, procs, 1, procs.String())
 		case <-ctx.Done():
-			time.Sleep(3000 * time.Millisecond)
-			procs, err := helperFullProcessList(dbStd)
-			assert.NoError(t, err)
-			procs = procs.Filter(filterDB, filterState)
-			assert.Len(t, procs, 0)
+			assert.Equal(t, context.DeadlineExceeded, <-done)
+			procs, err := helperFullProcessList(systemdb)
+			require.NoError(t, err)
+			procs = procs.Filter(filterState, filterInfo)
+			assert.Len(t, procs, 0, procs.String())
 			break Loop
 		}
 	}
 }
 
 type mySQLProcInfo struct {
-	ID      int64   `db:"Id"`
-	User    string  `db:"User"`
-	Host    string  `db:"Host"`
-	DB      string  `db:"db"`
-	Command string  `db:"Command"`
-	Time    int     `db:"Time"`
-	State   string  `db:"State"`
-	Info    *string `db:"Info"`
+	ID      int64
+	User    string
+	Host    string
+	DB      string
+	Command string
+	Time    int
+	State   string
+	Info    *string
 }
 type mySQLProcsInfo []mySQLProcInfo
 
-func helperFullProcessList(db *sql.DB) (mySQLProcsInfo, error) {
-	dbx := sqlx.NewDb(db, "mysql")
-	var procs []mySQLProcInfo
-	if err := dbx.Select(&procs, "show full processlist"); err != nil {
+func helperFullProcessList(db *stdSql.DB) (mySQLProcsInfo, error) {
+	rows, err := db.Query("show full processlist")
+	if err != nil {
 		return nil, err
 	}
-	return procs, nil
+	defer rows.Close()
+
+	var procs []mySQLProcInfo
+	for rows.Next() {
+		var m mySQLProcInfo
+		if err := rows.Scan(&m.ID, &m.User, &m.Host, &m.DB, &m.Command, &m.Time, &m.State, &m.Info); err != nil {
+			return nil, err
+		}
+		procs = append(procs, m)
+	}
+	return procs, rows.Err()
 }
 
 func (ms mySQLProcsInfo) String() string {
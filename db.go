@@ -6,6 +6,8 @@ import (
 	"context"
 	stdSql "database/sql"
 	"database/sql/driver"
+	"errors"
+	"runtime"
 	"sync"
 	"time"
 
@@ -113,14 +115,14 @@ func OpenDB(c driver.Connector) *DB {
 
 	var wg sync.WaitGroup
 
+	wg.Add(2)
+
 	go func() {
-		wg.Add(1)
 		defer wg.Done()
 		mp = stdSql.OpenDB(c)
 	}()
 
 	go func() {
-		wg.Add(1)
 		defer wg.Done()
 		kp = stdSql.OpenDB(c)
 		kp.SetMaxOpenConns(1)
@@ -158,6 +160,59 @@ type DB struct {
 	// KillTimeout sets how long to attempt sending the KILL signal.
 	// A value of zero is equivalent to no time limit (not recommended).
 	KillTimeout time.Duration
+
+	// Replicas is an optional set of read replicas. See ReadConn.
+	// The replicas are closed when the DB is closed.
+	Replicas *ReplicaSet
+
+	// TxMonitor is an optional monitor that reports long running transactions.
+	TxMonitor *TxMonitor
+
+	// Interceptors wrap every operation run on a Conn, Tx or Stmt (e.g. for logging,
+	// metrics, tracing, query rewriting or access control).
+	// The first interceptor is the outermost.
+	Interceptors []Interceptor
+
+	// Tracer is an optional tracer used to create spans for every operation run
+	// on a Conn, Tx or Stmt, and for KILL signals. The span of a query ends once
+	// its rows are closed.
+	Tracer Tracer
+
+	// Tags are prepended as an SQL comment to every query run on a Conn, Tx or Stmt,
+	// along with the tags added to the context using WithTags.
+	// For example: map[string]string{"app": "api"}.
+	Tags map[string]string
+
+	// VerifyKill sets whether information_schema.PROCESSLIST is checked to confirm
+	// that the connection is still running the query before a KILL signal is sent.
+	// A unique query_id tag is added to every query so that a later query with the
+	// same tags is not mistaken for it (a prepared statement's query_id is set when it
+	// is prepared). This reduces the chance of a late KILL signal canceling a different
+	// query, but can not rule it out since the query can finish after the check.
+	// Interceptors may rewrite the query but must not remove its query_id tag.
+	VerifyKill bool
+
+	// Logger is an optional logger used to log queries run on a Conn, Tx or Stmt,
+	// cancelations, KILL signals and leaked Conn, Tx, Stmt and Rows.
+	Logger Logger
+
+	// Redact sets how query arguments are redacted before they are logged.
+	// If set, the literals in logged queries are also replaced using SanitizeQuery.
+	// If nil, queries and arguments are logged unchanged.
+	Redact *Redaction
+
+	// TrackHandles sets whether every Conn, Tx, Stmt and Rows obtained from the DB
+	// (and every goroutine started by ExecContext) is tracked so that leaks can be
+	// reported by Leaks. It is intended for tests and must be set before the DB is used.
+	TrackHandles bool
+
+	statsOnce sync.Once
+	stats     *cancelStats
+
+	// Used to generate the query_id tag when VerifyKill is set
+	queryIDs uint64
+
+	handles handleTracker
 }
 
 // Begin starts a transaction. The default isolation level is dependent on
@@ -186,17 +241,35 @@ func (db *DB) BeginTx(ctx context.Context, opts *stdSql.TxOptions) (*stdSql.Tx,
 //
 // It is rare to Close a DB, as the DB handle is meant to be
 // long-lived and shared between many goroutines.
+//
+// The Replicas are also closed. Any errors from closing them are
+// joined with the error from closing the pool.
 func (db *DB) Close() error {
 
-	if db.KillerPool == db.DB {
-		return db.DB.Close()
+	if db.TxMonitor != nil {
+		db.TxMonitor.Stop()
 	}
 
-	if db.KillerPool != nil {
+	var errs []error
+
+	if db.Replicas != nil {
+		db.Replicas.Stop()
+		for _, r := range db.Replicas.Replicas {
+			if err := r.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if db.KillerPool != nil && db.KillerPool != db.DB {
 		db.KillerPool.Close()
 	}
 
-	return db.DB.Close()
+	if err := db.DB.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
 }
 
 // Conn returns a single connection by either opening a new connection
@@ -208,6 +281,21 @@ func (db *DB) Close() error {
 // calling Conn.Close.
 func (db *DB) Conn(ctx context.Context) (*Conn, error) {
 
+	if fp, ok := db.DB.(*failoverPool); ok {
+		// The connection (and its KILL signals) is bound to the current primary
+		conn, err := fp.conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		conn.db = db
+		conn.kto = db.KillTimeout
+		if db.Logger != nil {
+			runtime.SetFinalizer(conn, (*Conn).leaked)
+		}
+		db.trackConn(conn)
+		return conn, nil
+	}
+
 	// Obtain an exclusive connection
 	conn, err := db.DB.Conn(ctx)
 	if err != nil {
@@ -224,10 +312,74 @@ func (db *DB) Conn(ctx context.Context) (*Conn, error) {
 		return nil, err
 	}
 
-	if db.KillerPool == nil {
-		return &Conn{conn, db.DB, connectionID, db.KillTimeout}, nil
+	killerPool := db.KillerPool
+	if killerPool == nil {
+		killerPool = db.DB
+	}
+	c := &Conn{session: session{db: db, killerPool: killerPool, connectionID: connectionID, kto: db.KillTimeout}, conn: conn}
+	if db.Logger != nil {
+		runtime.SetFinalizer(c, (*Conn).leaked)
+	}
+	db.trackConn(c)
+	return c, nil
+}
+
+// SQLConn is the same as Conn except that it returns the SQLMockableConn interface.
+// It allows DB to satisfy the SQLDB interface.
+func (db *DB) SQLConn(ctx context.Context) (SQLMockableConn, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return &Conn{conn, db.KillerPool, connectionID, db.KillTimeout}, nil
+	return sqlConn{conn}, nil
+}
+
+// sqlConn adapts a Conn to the SQLMockableConn interface.
+type sqlConn struct {
+	*Conn
+}
+
+func (c sqlConn) BeginTx(ctx context.Context, opts *stdSql.TxOptions) (SQLTx, error) {
+	tx, err := c.Conn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (c sqlConn) PrepareContext(ctx context.Context, query string) (SQLStmt, error) {
+	stmt, err := c.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// ReadConn returns a single connection suitable for read-only queries.
+//
+// If Replicas is set, the connection is obtained from a replica whose replication
+// lag is within the configured limit. If every replica is stale (or none are
+// reachable), the connection is obtained from the primary instead.
+// A connection obtained from a replica is configured by db, except for
+// the KillerPool and KillTimeout.
+//
+// Every Conn must be returned to the database pool after use by
+// calling Conn.Close.
+func (db *DB) ReadConn(ctx context.Context) (*Conn, error) {
+
+	if db.Replicas != nil {
+		if replica := db.Replicas.pick(); replica != nil {
+			conn, err := db.replicaConn(ctx, replica)
+			if err == nil {
+				return conn, nil
+			}
+			if ctx.Err() != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return db.Conn(ctx)
 }
 
 // Driver returns the database's underlying driver.
@@ -348,6 +500,7 @@ func (db *DB) SetMaxOpenConns(n int) {
 }
 
 // Stats returns database statistics.
+// See CancelStats for statistics about canceled queries.
 func (db *DB) Stats() stdSql.DBStats {
 	return db.DB.Stats()
 }
@@ -0,0 +1,38 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL server error numbers.
+// See: https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+const (
+	erLockWaitTimeout         = 1205
+	erLockDeadlock            = 1213
+	erOptionPreventsStatement = 1290
+)
+
+// mysqlErrNumber returns the MySQL error number of err.
+func mysqlErrNumber(err error) (uint16, bool) {
+	var mErr *mysql.MySQLError
+	if errors.As(err, &mErr) {
+		return mErr.Number, true
+	}
+	return 0, false
+}
+
+// isConnErr reports whether err indicates that the server could not be reached
+// or the connection was lost.
+func isConnErr(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
@@ -0,0 +1,111 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+)
+
+// DefaultBatchSize is the number of rows in a Batch if the size is not set.
+const DefaultBatchSize = 1024
+
+// Batch is a columnar batch of up to size rows. It is laid out like an Apache Arrow
+// record batch but it is not encoded using Arrow's format: WriteBatches writes
+// each Batch as JSON.
+type Batch struct {
+	Columns []string        `json:"columns"`
+	Types   []string        `json:"types"` // Database type of each column (e.g. "VARCHAR")
+	Len     int             `json:"length"`
+	Values  [][]interface{} `json:"values"` // Values[column][row], converted using Rows.ScanSlice
+}
+
+// reset empties the batch while retaining its memory.
+func (b *Batch) reset() {
+	for i := range b.Values {
+		for j := range b.Values[i] {
+			b.Values[i][j] = nil // Release for garbage collection
+		}
+		b.Values[i] = b.Values[i][:0]
+	}
+	b.Len = 0
+}
+
+// Batches reads the rows in batches of up to size rows, calls fn with each batch
+// and closes the rows. The Batch is reused, so fn must not retain it. If fn returns
+// an error (or ctx is canceled), the query is killed and the error is returned.
+// It returns the number of rows in the batches that fn processed successfully.
+func Batches(ctx context.Context, rows *sql.Rows, size int, fn func(b *Batch) error) (int64, error) {
+	defer rows.Close()
+
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+
+	b := &Batch{
+		Columns: make([]string, len(cts)),
+		Types:   make([]string, len(cts)),
+		Values:  make([][]interface{}, len(cts)),
+	}
+	for i, ct := range cts {
+		b.Columns[i] = ct.Name()
+		b.Types[i] = ct.DatabaseTypeName()
+		b.Values[i] = make([]interface{}, 0, size)
+	}
+
+	var n int64
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			rows.Kill()
+			return n, err
+		}
+
+		vals, err := rows.ScanSlice()
+		if err != nil {
+			rows.Kill()
+			return n, err
+		}
+		for i, v := range vals {
+			b.Values[i] = append(b.Values[i], v)
+		}
+		b.Len++
+
+		if b.Len == size {
+			if err := fn(b); err != nil {
+				rows.Kill()
+				return n, err
+			}
+			n += int64(b.Len)
+			b.reset()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+
+	if b.Len > 0 {
+		if err := fn(b); err != nil {
+			return n, err
+		}
+		n += int64(b.Len)
+	}
+	return n, nil
+}
+
+// WriteBatches writes the rows to w in batches of up to size rows and closes them.
+// Each Batch is written as a JSON object on its own line. It returns the number
+// of rows written.
+func WriteBatches(ctx context.Context, w io.Writer, rows *sql.Rows, size int) (int64, error) {
+	enc := json.NewEncoder(w)
+	return Batches(ctx, rows, size, func(b *Batch) error {
+		return enc.Encode(b)
+	})
+}
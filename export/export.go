@@ -0,0 +1,195 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+// Package export streams the Rows of a github.com/rocketlaunchr/mysql-go query
+// to an io.Writer as CSV, NDJSON or columnar JSON batches. Memory use is bounded
+// by a single row (or batch) regardless of the size of the result set.
+//
+// If the context is canceled mid-stream (or writing fails), a KILL signal is sent
+// and writing stops, rather than reading the remaining rows. The context is
+// usually the one used to run the query.
+//
+//	rows, err := conn.QueryContext(ctx, "SELECT * FROM orders")
+//	if err != nil {
+//	   return err
+//	}
+//	n, err := export.WriteCSV(ctx, w, rows, nil)
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+)
+
+// CSVOptions configures WriteCSV.
+type CSVOptions struct {
+
+	// Comma is the field delimiter. A value of zero defaults to ','.
+	Comma rune
+
+	// Null is written for NULL values. It defaults to an empty string.
+	// MySQL's LOAD DATA uses `\N`.
+	Null string
+
+	// NoHeader omits the header record of column names.
+	NoHeader bool
+}
+
+// WriteCSV writes the rows to w as CSV and closes them. Values are converted
+// using Rows.ScanSlice and formatted as text. Times are formatted in MySQL's
+// DATETIME format. It returns the number of rows written.
+func WriteCSV(ctx context.Context, w io.Writer, rows *sql.Rows, opts *CSVOptions) (int64, error) {
+	defer rows.Close()
+
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	if !opts.NoHeader {
+		if err := cw.Write(cols); err != nil {
+			rows.Kill()
+			return 0, err
+		}
+	}
+
+	var (
+		n      int64
+		record = make([]string, len(cols))
+	)
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			rows.Kill()
+			return n, err
+		}
+
+		vals, err := rows.ScanSlice()
+		if err != nil {
+			rows.Kill()
+			return n, err
+		}
+
+		for i, v := range vals {
+			if v == nil {
+				record[i] = opts.Null
+			} else {
+				record[i] = formatText(v)
+			}
+		}
+
+		if err := cw.Write(record); err != nil {
+			rows.Kill()
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+
+	cw.Flush()
+	return n, cw.Error()
+}
+
+// WriteNDJSON writes the rows to w as newline-delimited JSON objects (keyed by
+// column name, in column order) and closes them. Values are converted using
+// Rows.ScanSlice. []byte values are encoded as base64 strings and JSON columns
+// are embedded as is. It returns the number of rows written.
+func WriteNDJSON(ctx context.Context, w io.Writer, rows *sql.Rows) (int64, error) {
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	// Encode the keys once
+	keys := make([][]byte, len(cols))
+	for i, col := range cols {
+		key, _ := json.Marshal(col)
+		keys[i] = append(key, ':')
+	}
+
+	var (
+		n  int64
+		bw = bufio.NewWriter(w)
+	)
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			rows.Kill()
+			return n, err
+		}
+
+		vals, err := rows.ScanSlice()
+		if err != nil {
+			rows.Kill()
+			return n, err
+		}
+
+		bw.WriteByte('{')
+		for i, v := range vals {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			bw.Write(keys[i])
+
+			b, err := json.Marshal(v)
+			if err != nil {
+				rows.Kill()
+				return n, fmt.Errorf("export: column %q: %v", cols[i], err)
+			}
+			bw.Write(b)
+		}
+		if _, err := bw.WriteString("}\n"); err != nil {
+			// bufio.Writer's errors are sticky so this reports any earlier failure
+			rows.Kill()
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+
+	return n, bw.Flush()
+}
+
+// mysqlDatetime is MySQL's DATETIME format.
+const mysqlDatetime = "2006-01-02 15:04:05.999999"
+
+// formatText formats a value returned by Rows.ScanSlice as text.
+func formatText(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case json.RawMessage:
+		return string(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case time.Time:
+		return x.Format(mysqlDatetime)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+// query runs a query using rec and returns the rows along with the id of the
+// connection they are read from. The connection and pool are closed when the test ends.
+func query(t *testing.T, ctx context.Context) (*sqltest.Recorder, string, *sql.Rows) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT id`).WillReturnRows([]string{"id", "name", "note"},
+		[]interface{}{1, "alice", "says \"hi\""},
+		[]interface{}{2, "bob", nil},
+		[]interface{}{3, "carol", "a,b"},
+	)
+
+	pool := rec.DB()
+	t.Cleanup(func() { pool.Close() })
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	var connectionID string
+	require.NoError(t, conn.QueryRowContext(context.Background(), "SELECT CONNECTION_ID()").Scan(&connectionID))
+
+	rows, err := conn.QueryContext(ctx, "SELECT id, name, note FROM users")
+	require.NoError(t, err)
+	return rec, connectionID, rows
+}
+
+func TestWriteCSV(t *testing.T) {
+	_, _, rows := query(t, context.Background())
+
+	var buf bytes.Buffer
+	n, err := WriteCSV(context.Background(), &buf, rows, &CSVOptions{Null: `\N`})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Equal(t, "id,name,note\n1,alice,\"says \"\"hi\"\"\"\n2,bob,\\N\n3,carol,\"a,b\"\n", buf.String())
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	_, _, rows := query(t, context.Background())
+
+	var buf bytes.Buffer
+	n, err := WriteNDJSON(context.Background(), &buf, rows)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Equal(t, `{"id":1,"name":"alice","note":"says \"hi\""}
+{"id":2,"name":"bob","note":null}
+{"id":3,"name":"carol","note":"a,b"}
+`, buf.String())
+}
+
+func TestWriteBatches(t *testing.T) {
+	_, _, rows := query(t, context.Background())
+
+	var buf bytes.Buffer
+	n, err := WriteBatches(context.Background(), &buf, rows, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Equal(t, `{"columns":["id","name","note"],"types":["","",""],"length":2,"values":[[1,2],["alice","bob"],["says \"hi\"",null]]}
+{"columns":["id","name","note"],"types":["","",""],"length":1,"values":[[3],["carol"],["a,b"]]}
+`, buf.String())
+}
+
+func TestBatchesKill(t *testing.T) {
+	rec, connectionID, rows := query(t, context.Background())
+
+	// Only the rows of the batches processed successfully are counted
+	var batches int
+	failed := errors.New("disk full")
+	n, err := Batches(context.Background(), rows, 1, func(b *Batch) error {
+		batches++
+		if batches == 2 {
+			return failed
+		}
+		return nil
+	})
+	assert.Equal(t, failed, err)
+	assert.Equal(t, int64(1), n)
+	assert.True(t, rec.Killed(connectionID))
+}
+
+func TestBatchesCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rec, connectionID, rows := query(t, ctx)
+
+	var batches int
+	n, err := Batches(ctx, rows, 1, func(b *Batch) error {
+		batches++
+		cancel()
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, batches)
+	assert.Equal(t, int64(1), n)
+	assert.True(t, rec.Killed(connectionID))
+}
@@ -0,0 +1,326 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	stdSql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNoPrimary is returned by a DB opened using OpenFailover when none of
+// the hosts are writable.
+var ErrNoPrimary = errors.New("sql: no writable primary found")
+
+// discoverTimeout is the maximum time taken to discover the primary.
+const discoverTimeout = 10 * time.Second
+
+// noPrimary is used to return ErrNoPrimary from QueryRow.
+// It is opened on first use.
+var (
+	noPrimary     *stdSql.DB
+	noPrimaryOnce sync.Once
+)
+
+// noPrimaryRow returns a row whose error is ErrNoPrimary.
+func noPrimaryRow(ctx context.Context, query string, args ...interface{}) *stdSql.Row {
+	noPrimaryOnce.Do(func() {
+		noPrimary = stdSql.OpenDB(errConnector{ErrNoPrimary})
+	})
+	return noPrimary.QueryRowContext(ctx, query, args...)
+}
+
+// errConnector is a driver.Connector that fails to connect with err.
+type errConnector struct{ err error }
+
+func (c errConnector) Connect(context.Context) (driver.Conn, error) { return nil, c.err }
+func (c errConnector) Driver() driver.Driver                        { return nil }
+
+// OpenFailover opens a database that is replicated across multiple hosts.
+// Each data source name must refer to a different host.
+//
+// The writable primary is discovered by checking @@read_only on each host
+// (@@super_read_only implies @@read_only). The primary is re-discovered
+// when a connection can not be established or when the server rejects a write
+// because it is read-only (error 1290). If none of the hosts are writable,
+// operations fail with ErrNoPrimary.
+//
+// Each host is given its own KillerPool. A Conn always sends KILL signals
+// to the host that it was obtained from, even after the primary has changed.
+//
+// Like Open, OpenFailover will just validate its arguments without creating a
+// connection to the database. The primary is discovered on first use.
+func OpenFailover(driverName string, dataSourceNames []string) (*DB, error) {
+
+	if len(dataSourceNames) == 0 {
+		return nil, errors.New("sql: no data source names provided")
+	}
+
+	fp := &failoverPool{}
+
+	for _, dsn := range dataSourceNames {
+		host, err := Open(driverName, dsn)
+		if err != nil {
+			fp.Close()
+			return nil, err
+		}
+		fp.hosts = append(fp.hosts, host)
+	}
+
+	return &DB{DB: fp}, nil
+}
+
+// failoverPool is a StdSQLDBExtra that directs all operations to the current
+// primary of a group of hosts.
+type failoverPool struct {
+	hosts []*DB
+
+	lock    sync.RWMutex
+	primary *DB
+	group   singleflight.Group
+}
+
+// current returns the current primary. If the primary is not known, it is discovered.
+// If no primary can be found, ErrNoPrimary is returned.
+func (fp *failoverPool) current(ctx context.Context) (*DB, error) {
+	fp.lock.RLock()
+	primary := fp.primary
+	fp.lock.RUnlock()
+
+	if primary != nil {
+		return primary, nil
+	}
+
+	select {
+	case res := <-fp.discover():
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*DB), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// discover finds the writable primary in the background. Concurrent calls
+// share a single discovery.
+func (fp *failoverPool) discover() <-chan singleflight.Result {
+	return fp.group.DoChan("primary", func() (interface{}, error) {
+		ctx, cancelFunc := context.WithTimeout(context.Background(), discoverTimeout)
+		defer cancelFunc()
+		return fp.findPrimary(ctx)
+	})
+}
+
+// findPrimary checks each host to find the writable primary.
+func (fp *failoverPool) findPrimary(ctx context.Context) (*DB, error) {
+
+	writable := make([]bool, len(fp.hosts))
+
+	var wg sync.WaitGroup
+	wg.Add(len(fp.hosts))
+	for i := range fp.hosts {
+		go func(i int) {
+			defer wg.Done()
+			var readOnly bool
+			err := fp.hosts[i].DB.QueryRowContext(ctx, "SELECT @@GLOBAL.read_only").Scan(&readOnly)
+			writable[i] = err == nil && !readOnly
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range fp.hosts {
+		if writable[i] {
+			fp.lock.Lock()
+			fp.primary = fp.hosts[i]
+			fp.lock.Unlock()
+			return fp.hosts[i], nil
+		}
+	}
+
+	// Operations must not be sent to a former primary
+	fp.lock.Lock()
+	fp.primary = nil
+	fp.lock.Unlock()
+	return nil, ErrNoPrimary
+}
+
+// noteErr re-discovers the primary in the background if err indicates that
+// the primary has changed or is unreachable.
+func (fp *failoverPool) noteErr(err error) {
+	if err == nil {
+		return
+	}
+
+	if num, ok := mysqlErrNumber(err); !(ok && num == erOptionPreventsStatement) && !isConnErr(err) {
+		return
+	}
+
+	fp.discover()
+}
+
+// noteErr informs the DB of an error so that the primary can be re-discovered
+// when it has changed.
+func (db *DB) noteErr(err error) {
+	if db == nil || err == nil {
+		return
+	}
+	if fp, ok := db.DB.(*failoverPool); ok {
+		fp.noteErr(err)
+	}
+}
+
+// conn returns a single connection to the current primary.
+func (fp *failoverPool) conn(ctx context.Context) (*Conn, error) {
+	primary, err := fp.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := primary.Conn(ctx)
+	fp.noteErr(err)
+	return conn, err
+}
+
+func (fp *failoverPool) Ping() error {
+	return fp.PingContext(context.Background())
+}
+
+func (fp *failoverPool) PingContext(ctx context.Context) error {
+	primary, err := fp.current(ctx)
+	if err != nil {
+		return err
+	}
+	err = primary.DB.PingContext(ctx)
+	fp.noteErr(err)
+	return err
+}
+
+func (fp *failoverPool) Exec(query string, args ...interface{}) (stdSql.Result, error) {
+	return fp.ExecContext(context.Background(), query, args...)
+}
+
+func (fp *failoverPool) ExecContext(ctx context.Context, query string, args ...interface{}) (stdSql.Result, error) {
+	primary, err := fp.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := primary.DB.ExecContext(ctx, query, args...)
+	fp.noteErr(err)
+	return res, err
+}
+
+func (fp *failoverPool) Prepare(query string) (*stdSql.Stmt, error) {
+	return fp.PrepareContext(context.Background(), query)
+}
+
+func (fp *failoverPool) PrepareContext(ctx context.Context, query string) (*stdSql.Stmt, error) {
+	primary, err := fp.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := primary.DB.PrepareContext(ctx, query)
+	fp.noteErr(err)
+	return stmt, err
+}
+
+func (fp *failoverPool) Query(query string, args ...interface{}) (*stdSql.Rows, error) {
+	return fp.QueryContext(context.Background(), query, args...)
+}
+
+func (fp *failoverPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*stdSql.Rows, error) {
+	primary, err := fp.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := primary.DB.QueryContext(ctx, query, args...)
+	fp.noteErr(err)
+	return rows, err
+}
+
+func (fp *failoverPool) QueryRow(query string, args ...interface{}) *stdSql.Row {
+	return fp.QueryRowContext(context.Background(), query, args...)
+}
+
+func (fp *failoverPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *stdSql.Row {
+	primary, err := fp.current(ctx)
+	if err != nil {
+		return noPrimaryRow(ctx, query, args...)
+	}
+	row := primary.DB.QueryRowContext(ctx, query, args...)
+	fp.noteErr(row.Err())
+	return row
+}
+
+func (fp *failoverPool) Conn(ctx context.Context) (*stdSql.Conn, error) {
+	primary, err := fp.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := primary.DB.Conn(ctx)
+	fp.noteErr(err)
+	return conn, err
+}
+
+func (fp *failoverPool) Begin() (*stdSql.Tx, error) {
+	return fp.BeginTx(context.Background(), nil)
+}
+
+func (fp *failoverPool) BeginTx(ctx context.Context, opts *stdSql.TxOptions) (*stdSql.Tx, error) {
+	primary, err := fp.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := primary.DB.BeginTx(ctx, opts)
+	fp.noteErr(err)
+	return tx, err
+}
+
+func (fp *failoverPool) Close() error {
+	var err error
+	for _, host := range fp.hosts {
+		if cErr := host.Close(); cErr != nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+func (fp *failoverPool) Driver() driver.Driver {
+	return fp.hosts[0].DB.Driver()
+}
+
+func (fp *failoverPool) SetConnMaxLifetime(d time.Duration) {
+	for _, host := range fp.hosts {
+		host.DB.SetConnMaxLifetime(d)
+	}
+}
+
+func (fp *failoverPool) SetMaxIdleConns(n int) {
+	for _, host := range fp.hosts {
+		host.DB.SetMaxIdleConns(n)
+	}
+}
+
+func (fp *failoverPool) SetMaxOpenConns(n int) {
+	for _, host := range fp.hosts {
+		host.DB.SetMaxOpenConns(n)
+	}
+}
+
+// Stats returns the statistics of the current primary. Unlike the other methods,
+// it does not discover the primary if it is not known.
+func (fp *failoverPool) Stats() stdSql.DBStats {
+	fp.lock.RLock()
+	primary := fp.primary
+	fp.lock.RUnlock()
+
+	if primary == nil {
+		return stdSql.DBStats{}
+	}
+	return primary.DB.Stats()
+}
@@ -0,0 +1,192 @@
+package sql_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+// newHost starts a server that reports whether it is read-only.
+func newHost(t *testing.T, readOnly bool, delay time.Duration) *sqltest.Server {
+	t.Helper()
+
+	srv, err := sqltest.NewServer()
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+
+	srv.Handle(`^SELECT @@GLOBAL.read_only`, sqltest.Response{
+		Columns: []string{"@@GLOBAL.read_only"},
+		Rows:    [][]interface{}{{readOnly}},
+		Delay:   delay,
+	})
+	return srv
+}
+
+// count returns the number of queries received by srv that start with prefix.
+func count(srv *sqltest.Server, prefix string) int {
+	var n int
+	for _, q := range srv.Queries() {
+		if strings.HasPrefix(q, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func openFailover(t *testing.T, hosts ...*sqltest.Server) *sql.DB {
+	t.Helper()
+
+	var dsns []string
+	for _, h := range hosts {
+		dsns = append(dsns, h.DSN())
+	}
+	pool, err := sql.OpenFailover("mysql", dsns)
+	require.NoError(t, err)
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func TestFailoverDiscover(t *testing.T) {
+	replica := newHost(t, true, 0)
+	primary := newHost(t, false, 0)
+	pool := openFailover(t, replica, primary)
+
+	_, err := pool.ExecContext(context.Background(), "INSERT INTO users VALUES (1)")
+	require.NoError(t, err)
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	_, err = conn.ExecContext(context.Background(), "INSERT INTO users VALUES (2)")
+	require.NoError(t, err)
+	conn.Close()
+
+	assert.Equal(t, 2, count(primary, "INSERT"))
+	assert.Equal(t, 0, count(replica, "INSERT"))
+
+	// The primary is only discovered once
+	assert.Equal(t, 1, count(primary, "SELECT @@GLOBAL.read_only"))
+}
+
+func TestFailoverNoPrimary(t *testing.T) {
+	pool := openFailover(t, newHost(t, true, 0), newHost(t, true, 0))
+
+	_, err := pool.ExecContext(context.Background(), "INSERT INTO users VALUES (1)")
+	assert.Equal(t, sql.ErrNoPrimary, err)
+
+	_, err = pool.Conn(context.Background())
+	assert.Equal(t, sql.ErrNoPrimary, err)
+
+	var id int
+	err = pool.QueryRowContext(context.Background(), "SELECT id FROM users").Scan(&id)
+	assert.Equal(t, sql.ErrNoPrimary, err)
+}
+
+func TestFailoverConcurrentDiscovery(t *testing.T) {
+	primary := newHost(t, false, 100*time.Millisecond)
+	pool := openFailover(t, primary)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pool.ExecContext(context.Background(), "INSERT INTO users VALUES (1)")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, count(primary, "SELECT @@GLOBAL.read_only"))
+	assert.Equal(t, 5, count(primary, "INSERT"))
+}
+
+func TestFailoverRediscover(t *testing.T) {
+	readOnly := &mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --read-only option"}
+
+	primary := newHost(t, false, 0)
+	primary.Handle(`^INSERT`, sqltest.Response{Err: readOnly})
+	primary.Handle(`^SELECT id FROM users FOR UPDATE`, sqltest.Response{Err: readOnly})
+	pool := openFailover(t, primary)
+
+	_, err := pool.ExecContext(context.Background(), "INSERT INTO users VALUES (1)")
+	assert.Error(t, err)
+	assert.Eventually(t, func() bool {
+		return count(primary, "SELECT @@GLOBAL.read_only") == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// Errors returned by QueryRow also trigger a re-discovery
+	var id int
+	err = pool.QueryRowContext(context.Background(), "SELECT id FROM users FOR UPDATE").Scan(&id)
+	assert.Error(t, err)
+	assert.Eventually(t, func() bool {
+		return count(primary, "SELECT @@GLOBAL.read_only") == 3
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFailoverContextCanceled(t *testing.T) {
+	pool := openFailover(t, newHost(t, false, time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := pool.ExecContext(ctx, "INSERT INTO users VALUES (1)")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestFailoverPrimaryLost(t *testing.T) {
+	replica := newHost(t, true, 0)
+	primary := newHost(t, false, 0)
+	pool := openFailover(t, replica, primary)
+
+	_, err := pool.ExecContext(context.Background(), "INSERT INTO users VALUES (1)")
+	require.NoError(t, err)
+
+	// The former primary is no longer used once no host is writable
+	require.NoError(t, primary.Close())
+	_, err = pool.ExecContext(context.Background(), "INSERT INTO users VALUES (2)")
+	assert.Error(t, err)
+	assert.Eventually(t, func() bool {
+		_, err := pool.ExecContext(context.Background(), "INSERT INTO users VALUES (3)")
+		return err == sql.ErrNoPrimary
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFailoverQueryRediscover(t *testing.T) {
+	replica := newHost(t, true, 0)
+	primary := newHost(t, false, 0)
+	pool := openFailover(t, replica, primary)
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, 1, count(replica, "SELECT @@GLOBAL.read_only"))
+
+	// Connection errors returned by Query through a Conn trigger a re-discovery
+	require.NoError(t, primary.Close())
+	_, err = conn.QueryContext(context.Background(), "SELECT id FROM users")
+	assert.Error(t, err)
+	assert.Eventually(t, func() bool {
+		return count(replica, "SELECT @@GLOBAL.read_only") == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFailoverStatsDoesNotDiscover(t *testing.T) {
+	primary := newHost(t, false, time.Second)
+	pool := openFailover(t, primary)
+
+	start := time.Now()
+	assert.Equal(t, 0, pool.Stats().OpenConnections)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, 0, count(primary, "SELECT @@GLOBAL.read_only"))
+}
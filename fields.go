@@ -0,0 +1,167 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	stdSql "database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+var (
+	scannerType = reflect.TypeOf((*stdSql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// isStruct reports whether t is a struct whose fields the columns are mapped to.
+// Structs that implement stdSql.Scanner (such as stdSql.NullString) and time.Time
+// are scanned as a single column.
+func isStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PtrTo(t).Implements(scannerType)
+}
+
+// structMapping maps column names (in lower case) to the index of a struct's fields.
+type structMapping map[string][]int
+
+// structMappings caches the structMapping of each struct type.
+var structMappings sync.Map // map[reflect.Type]structMapping
+
+// mappingOf returns the structMapping of the struct type t.
+func mappingOf(t reflect.Type) structMapping {
+	if m, ok := structMappings.Load(t); ok {
+		return m.(structMapping)
+	}
+
+	m := structMapping{}
+	depths := map[string]int{}
+	addFields(m, depths, t, nil)
+
+	structMappings.Store(t, m)
+	return m
+}
+
+// addFields adds the fields of t (and its embedded structs) to m.
+// Fields of shallower structs take precedence over fields of embedded structs.
+func addFields(m structMapping, depths map[string]int, t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(index)] = i
+
+		if f.Anonymous && tag == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				if f.PkgPath != "" {
+					continue // Can't be allocated
+				}
+				ft = ft.Elem()
+			}
+			if isStruct(ft) {
+				addFields(m, depths, ft, idx)
+				continue
+			}
+		}
+
+		if f.PkgPath != "" {
+			continue // Unexported
+		}
+
+		name := tag
+		if name == "" {
+			name = snakeCase(f.Name)
+		}
+		name = strings.ToLower(name)
+
+		if d, exists := depths[name]; exists && d <= len(index) {
+			continue
+		}
+		depths[name] = len(index)
+		m[name] = idx
+	}
+}
+
+// snakeCase converts a field name to snake_case. For example: UserID is converted to user_id.
+func snakeCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word unless within an acronym (except its last letter)
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// fieldByIndex returns the field of v with the given index, allocating
+// nil embedded structs along the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// structTargets returns pointers to the fields of the struct pointed at by dest
+// that the columns are scanned into. Each column is mapped to the field with a
+// matching `db` tag or snake_case name (case-insensitive).
+func structTargets(dest interface{}, columns []string) ([]interface{}, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || !isStruct(v.Type().Elem()) {
+		return nil, fmt.Errorf("sql: destination must be a non-nil pointer to a struct, not %T", dest)
+	}
+	v = v.Elem()
+
+	m := mappingOf(v.Type())
+	targets := make([]interface{}, len(columns))
+	for i, col := range columns {
+		index, ok := m[strings.ToLower(col)]
+		if !ok {
+			return nil, fmt.Errorf("sql: no field for column %q in %s", col, v.Type())
+		}
+		targets[i] = fieldByIndex(v, index).Addr().Interface()
+	}
+	return targets, nil
+}
+
+// scanTargets returns the pointers that the columns of a row are scanned into.
+// dest must be a non-nil pointer.
+//
+// If dest points to a struct, the columns are mapped to its fields (see structTargets).
+// Otherwise, there must be exactly one column.
+func scanTargets(dest interface{}, columns []string) ([]interface{}, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, fmt.Errorf("sql: destination must be a non-nil pointer, not %T", dest)
+	}
+
+	if isStruct(v.Type().Elem()) {
+		return structTargets(dest, columns)
+	}
+	if len(columns) != 1 {
+		return nil, fmt.Errorf("sql: expected 1 column for %s, not %d", v.Type().Elem(), len(columns))
+	}
+	return []interface{}{dest}, nil
+}
@@ -0,0 +1,20 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnakeCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"ID":         "id",
+		"Name":       "name",
+		"UserID":     "user_id",
+		"HTTPStatus": "http_status",
+		"CreatedAt":  "created_at",
+		"already_ok": "already_ok",
+	} {
+		assert.Equal(t, expected, snakeCase(name), name)
+	}
+}
@@ -1,3 +1,19 @@
 module github.com/rocketlaunchr/mysql-go
 
-go 1.12
+go 1.23.0
+
+require (
+	github.com/go-sql-driver/mysql v1.9.3
+	github.com/stretchr/testify v1.11.1
+	golang.org/x/sync v0.14.0
+)
+
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kr/pretty v0.3.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rogpeppe/go-internal v1.13.1 // indirect
+	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
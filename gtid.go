@@ -0,0 +1,135 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	stdSql "database/sql"
+	"encoding/base64"
+	"time"
+)
+
+// GTIDToken records the transactions that a replica must have applied before
+// it can serve a read that is consistent with earlier writes
+// (read-your-writes consistency).
+//
+// A GTIDToken can be serialized (e.g. into an HTTP session cookie) using String
+// or MarshalText and restored using ParseGTIDToken or UnmarshalText.
+type GTIDToken struct {
+
+	// GTIDSet is the set of transactions in MySQL's GTID set format.
+	GTIDSet string
+}
+
+// ParseGTIDToken restores a GTIDToken that was serialized using String.
+func ParseGTIDToken(s string) (GTIDToken, error) {
+	var t GTIDToken
+	err := t.UnmarshalText([]byte(s))
+	return t, err
+}
+
+// IsZero reports whether the token does not record any transactions.
+func (t GTIDToken) IsZero() bool {
+	return t.GTIDSet == ""
+}
+
+// String returns the token in a form that is safe to use in URLs and cookies.
+func (t GTIDToken) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(t.GTIDSet))
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (t GTIDToken) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (t *GTIDToken) UnmarshalText(text []byte) error {
+	set, err := base64.RawURLEncoding.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+	t.GTIDSet = string(set)
+	return nil
+}
+
+// LastGTID returns a token recording the transactions executed by the server,
+// including any writes that have completed on this connection.
+// It should be called after Conn.ExecContext or Tx.Commit.
+//
+// The driver does not expose session_track_gtids, so the server's
+// gtid_executed set is used. It is a superset of the session's own transactions:
+// it includes every transaction committed on the server by any client. A replica
+// must therefore apply all of them before ReadConnAfter uses it, so on a busy
+// server reads fall back to the primary more often than strictly necessary.
+// The set (and the token) also grows with the number of servers that have
+// accepted writes.
+func (c *Conn) LastGTID(ctx context.Context) (GTIDToken, error) {
+	var set string
+	err := c.QueryRowContext(ctx, "SELECT @@GLOBAL.gtid_executed").Scan(&set)
+	if err != nil {
+		return GTIDToken{}, err
+	}
+	return GTIDToken{GTIDSet: set}, nil
+}
+
+// waitForGTID waits until the server has applied every transaction in token.
+// It reports false if the wait timed out.
+func (c *Conn) waitForGTID(ctx context.Context, token GTIDToken, timeout time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	// WAIT_FOR_EXECUTED_GTID_SET waits forever if the timeout is 0 and rejects
+	// negative timeouts.
+	if timeout < time.Millisecond {
+		return false, nil
+	}
+
+	var res stdSql.NullInt64
+	err := c.QueryRowContext(ctx, "SELECT WAIT_FOR_EXECUTED_GTID_SET(?, ?)", token.GTIDSet, timeout.Seconds()).Scan(&res)
+	if err != nil {
+		return false, err
+	}
+	return res.Valid && res.Int64 == 0, nil
+}
+
+// ReadConnAfter returns a single connection suitable for read-only queries that
+// observes every transaction recorded in token.
+//
+// If Replicas is set, a replica is first made to wait until it has applied the
+// transactions. The wait is bounded by the context's deadline (or
+// ReplicaSet.MaxGTIDWait). If the replica does not catch up in time, the
+// connection is obtained from the primary instead.
+//
+// Every Conn must be returned to the database pool after use by
+// calling Conn.Close.
+func (db *DB) ReadConnAfter(ctx context.Context, token GTIDToken) (*Conn, error) {
+
+	if token.IsZero() {
+		return db.ReadConn(ctx)
+	}
+
+	if db.Replicas != nil {
+		if replica := db.Replicas.pick(); replica != nil {
+			conn, err := db.replicaConn(ctx, replica)
+			if err == nil {
+				timeout := db.Replicas.maxGTIDWait()
+				if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+					timeout = time.Until(deadline)
+				}
+
+				caughtUp, err := conn.waitForGTID(ctx, token, timeout)
+				if err == nil && caughtUp {
+					return conn, nil
+				}
+				conn.Close()
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	return db.Conn(ctx)
+}
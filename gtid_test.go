@@ -0,0 +1,130 @@
+package sql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+const gtidSet = "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5"
+
+func TestGTIDToken(t *testing.T) {
+	token := sql.GTIDToken{GTIDSet: gtidSet}
+
+	parsed, err := sql.ParseGTIDToken(token.String())
+	require.NoError(t, err)
+	assert.Equal(t, token, parsed)
+
+	_, err = sql.ParseGTIDToken("not base64!")
+	assert.Error(t, err)
+
+	assert.True(t, sql.GTIDToken{}.IsZero())
+	assert.False(t, token.IsZero())
+}
+
+func TestLastGTID(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`gtid_executed`).WillReturnRows([]string{"@@GLOBAL.gtid_executed"}, []interface{}{gtidSet})
+	pool := rec.DB()
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	token, err := conn.LastGTID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, gtidSet, token.GTIDSet)
+}
+
+// waits returns the timeouts of the WAIT_FOR_EXECUTED_GTID_SET statements
+// recorded by rec.
+func waits(rec *sqltest.Recorder) []float64 {
+	var timeouts []float64
+	for _, s := range rec.Statements() {
+		if s.Query == "SELECT WAIT_FOR_EXECUTED_GTID_SET(?, ?)" {
+			timeouts = append(timeouts, s.Args[1].(float64))
+		}
+	}
+	return timeouts
+}
+
+func TestReadConnAfter(t *testing.T) {
+	token := sql.GTIDToken{GTIDSet: gtidSet}
+
+	tests := []struct {
+		name    string
+		result  interface{}
+		replica bool
+	}{
+		{"caught up", int64(0), true},
+		{"timed out", int64(1), false},
+		{"not using GTIDs", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := sqltest.NewRecorder()
+			replica := sqltest.NewRecorder()
+			replica.Expect(`^SELECT lag`).WillReturnRows([]string{"lag"}, []interface{}{0.0})
+			replica.Expect(`^SELECT WAIT_FOR_EXECUTED_GTID_SET`).WillReturnRows([]string{"res"}, []interface{}{tt.result})
+
+			pool := primary.DB()
+			pool.Replicas = &sql.ReplicaSet{Replicas: []*sql.DB{replica.DB()}, MaxLag: time.Second, HeartbeatQuery: "SELECT lag FROM heartbeat"}
+			defer pool.Close()
+			pool.Replicas.Check(context.Background())
+
+			conn, err := pool.ReadConnAfter(context.Background(), token)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			before := len(replica.Statements())
+			_, err = conn.ExecContext(context.Background(), "SELECT 1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.replica, len(replica.Statements()) > before)
+			assert.Equal(t, []float64{1}, waits(replica))
+		})
+	}
+}
+
+func TestReadConnAfterTimeout(t *testing.T) {
+	replica := sqltest.NewRecorder()
+	replica.Expect(`^SELECT lag`).WillReturnRows([]string{"lag"}, []interface{}{0.0})
+	replica.Expect(`^SELECT WAIT_FOR_EXECUTED_GTID_SET`).WillReturnRows([]string{"res"}, []interface{}{int64(0)})
+
+	pool := sqltest.NewRecorder().DB()
+	pool.Replicas = &sql.ReplicaSet{
+		Replicas:       []*sql.DB{replica.DB()},
+		MaxLag:         time.Second,
+		HeartbeatQuery: "SELECT lag FROM heartbeat",
+		MaxGTIDWait:    time.Minute,
+	}
+	defer pool.Close()
+	pool.Replicas.Check(context.Background())
+
+	// The wait is bounded by the context's deadline
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	conn, err := pool.ReadConnAfter(ctx, sql.GTIDToken{GTIDSet: gtidSet})
+	require.NoError(t, err)
+	conn.Close()
+
+	if timeouts := waits(replica); assert.Len(t, timeouts, 1) {
+		assert.True(t, timeouts[0] > 0 && timeouts[0] <= 1, timeouts[0])
+	}
+
+	// The context has already expired
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	_, err = pool.ReadConnAfter(ctx, sql.GTIDToken{GTIDSet: gtidSet})
+	assert.Equal(t, context.Canceled, err)
+	assert.Len(t, waits(replica), 1)
+}
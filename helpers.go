@@ -4,6 +4,7 @@ package sql
 
 import (
 	"context"
+	"strings"
 	"time"
 )
 
@@ -11,12 +12,94 @@ import (
 // It is advised that db be another pool that the
 // connection was NOT derived from.
 func kill(db StdSQLDB, connectionID string, kto time.Duration) error {
+	return sendKill(db, `KILL QUERY ?`, connectionID, kto)
+}
 
-	if connectionID == "" {
+// kill sends a KILL QUERY signal and records the outcome in CancelStats.
+// If a Tracer is set, the operation's span is marked as killed and a child
+// span is created for the KILL signal.
+//
+// comment is the tag comment of the query being killed. If VerifyKill is set,
+// the KILL signal is only sent if the connection is still running the query.
+func (db *DB) kill(ctx context.Context, killerPool StdSQLDB, connectionID string, kto time.Duration, comment string) error {
+
+	if db == nil || connectionID == "" {
+		return kill(killerPool, connectionID, kto)
+	}
+
+	if db.VerifyKill && comment != "" && !stillRunning(killerPool, connectionID, comment, kto) {
+		db.cancelStats().recordKillSkipped()
+		db.log(ctx, LogInfo, "kill skipped", LogAttr{logKeyConnectionID, connectionID})
 		return nil
 	}
 
-	stmt := `KILL QUERY ?`
+	var span Span
+	if db.Tracer != nil {
+		if opSpan, ok := ctx.Value(spanKey{}).(Span); ok {
+			opSpan.SetAttribute(attrKilled, true)
+		}
+
+		_, span = db.Tracer.Start(ctx, "mysql KILL QUERY")
+		span.SetAttribute(attrSystem, "mysql")
+		span.SetAttribute(attrConnectionID, connectionID)
+	}
+
+	start := time.Now()
+	err := kill(killerPool, connectionID, kto)
+	latency := time.Since(start)
+
+	db.cancelStats().recordKill(latency, err)
+
+	if err != nil {
+		db.log(ctx, LogError, "kill failed", LogAttr{logKeyConnectionID, connectionID}, LogAttr{logKeyDuration, latency}, LogAttr{logKeyError, err})
+	} else {
+		db.log(ctx, LogInfo, "query killed", LogAttr{logKeyConnectionID, connectionID}, LogAttr{logKeyDuration, latency})
+	}
+
+	if span != nil {
+		span.SetAttribute(attrKillLatency, float64(latency)/float64(time.Millisecond))
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}
+	return err
+}
+
+// killConnection is used to terminate a connection. Unlike kill,
+// any open transaction is rolled back and its locks are released.
+func killConnection(db StdSQLDB, connectionID string, kto time.Duration) error {
+	return sendKill(db, `KILL CONNECTION ?`, connectionID, kto)
+}
+
+// killConnection sends a KILL CONNECTION signal and records the outcome in
+// CancelStats.
+func (db *DB) killConnection(ctx context.Context, killerPool StdSQLDB, connectionID string, kto time.Duration) error {
+
+	if db == nil || connectionID == "" {
+		return killConnection(killerPool, connectionID, kto)
+	}
+
+	start := time.Now()
+	err := killConnection(killerPool, connectionID, kto)
+	latency := time.Since(start)
+
+	db.cancelStats().recordKill(latency, err)
+
+	if err != nil {
+		db.log(ctx, LogError, "kill failed", LogAttr{logKeyConnectionID, connectionID}, LogAttr{logKeyDuration, latency}, LogAttr{logKeyError, err})
+	} else {
+		db.log(ctx, LogInfo, "connection killed", LogAttr{logKeyConnectionID, connectionID}, LogAttr{logKeyDuration, latency})
+	}
+	return err
+}
+
+// sendKill sends a KILL signal for the connection.
+func sendKill(db StdSQLDB, stmt string, connectionID string, kto time.Duration) error {
+
+	if connectionID == "" {
+		return nil
+	}
 
 	if kto == 0 {
 		_, err := db.Exec(stmt, connectionID)
@@ -34,3 +117,8 @@ func kill(db StdSQLDB, connectionID string, kto time.Duration) error {
 
 	return nil
 }
+
+// quoteIdentifier quotes a MySQL identifier such as a savepoint, table or column name.
+func quoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
@@ -0,0 +1,179 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	stdSql "database/sql"
+	"errors"
+)
+
+// Op identifies the kind of operation being intercepted.
+type Op int
+
+// Operations that can be intercepted.
+const (
+	OpExec Op = iota + 1
+	OpQuery
+	OpQueryRow
+	OpPrepare
+	OpBegin
+	OpCommit
+	OpRollback
+)
+
+// String returns the name of the operation.
+func (o Op) String() string {
+	switch o {
+	case OpExec:
+		return "Exec"
+	case OpQuery:
+		return "Query"
+	case OpQueryRow:
+		return "QueryRow"
+	case OpPrepare:
+		return "Prepare"
+	case OpBegin:
+		return "Begin"
+	case OpCommit:
+		return "Commit"
+	case OpRollback:
+		return "Rollback"
+	}
+	return "Unknown"
+}
+
+// Call describes an operation run on a Conn, Tx or Stmt.
+type Call struct {
+	Op Op
+
+	// Query is the query being run. Interceptors may rewrite it.
+	//
+	// For Begin, Commit and Rollback, Query is empty. For prepared statements,
+	// Query is the query the statement was prepared with (if known) and
+	// rewriting it has no effect.
+	Query string
+
+	// Args are the arguments for any placeholder parameters in the query.
+	// Interceptors may rewrite them.
+	Args []interface{}
+
+	// ConnectionID is the MySQL connection id the operation runs on.
+	ConnectionID string
+
+	// Stmt reports whether the operation is run using a prepared statement.
+	Stmt bool
+
+	// Result is the result of an Exec operation.
+	// It is set once the operation has completed successfully.
+	Result stdSql.Result
+
+	// Killed reports whether a KILL signal was sent because the context
+	// was canceled while the operation was running. For Query and QueryRow
+	// operations, it is only final once the functions registered using
+	// OnClose are called.
+	Killed bool
+
+	closeHooks []func()
+}
+
+// OnClose registers fn to be called once the operation has finished.
+// For Query and QueryRow operations that succeed, that is when the Rows are
+// closed (or Row's Scan method returns), since the query can still be killed
+// while the rows are being read. Otherwise, it is once the interceptors have returned.
+// Functions are called in the reverse order they were registered.
+func (c *Call) OnClose(fn func()) {
+	c.closeHooks = append(c.closeHooks, fn)
+}
+
+// close calls the functions registered using OnClose. They are only called once.
+func (c *Call) close() {
+	hooks := c.closeHooks
+	c.closeHooks = nil
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// Handler runs an operation.
+type Handler func(ctx context.Context, call *Call) error
+
+// errNextNotCalled is returned if an interceptor returns nil without running the operation.
+var errNextNotCalled = errors.New("sql: interceptor did not call next")
+
+// Interceptor wraps the running of an operation. It can inspect and modify the call
+// before calling next to continue running the operation, and inspect the outcome
+// after next returns. An Interceptor can prevent an operation from running
+// by returning an error without calling next. Returning nil without calling
+// next is an error.
+//
+// For Query and QueryRow operations, next returns once the query has started
+// returning rows. Unlike database/sql, where QueryRow defers errors until Scan,
+// next returns the error of a QueryRow operation so that interceptors see it.
+// The error is also returned by Row's Scan method.
+//
+// Cancelation (and the KILL signal) is handled after all interceptors have run.
+// Interceptors must pass on the context provided to next.
+type Interceptor func(ctx context.Context, call *Call, next Handler) error
+
+// intercept runs an operation through the DB's interceptors.
+// The first interceptor is the outermost.
+func (db *DB) intercept(ctx context.Context, call *Call, final Handler) (err error) {
+
+	defer func() {
+		// Query and QueryRow operations finish once their rows are closed
+		if err != nil || (call.Op != OpQuery && call.Op != OpQueryRow) {
+			call.close()
+		}
+	}()
+
+	if db == nil {
+		return final(ctx, call)
+	}
+
+	stats := db.cancelStats()
+	isQuery := stats.recordQuery(call.Op)
+	if isQuery {
+		defer stats.recordFinished(ctx)
+	}
+
+	var ran bool
+	h := func(ctx context.Context, call *Call) error {
+		ran = true
+		return final(ctx, call)
+	}
+
+	if db.Tracer != nil {
+		// Tracing is innermost so that the span records the query that is actually
+		// run and is available when the KILL signal is sent.
+		next := h
+		h = func(ctx context.Context, call *Call) error {
+			return db.trace(ctx, call, next)
+		}
+	}
+
+	for i := len(db.Interceptors) - 1; i >= 0; i-- {
+		interceptor, next := db.Interceptors[i], h
+		h = func(ctx context.Context, call *Call) error {
+			return interceptor(ctx, call, next)
+		}
+	}
+
+	if len(db.Interceptors) > 0 {
+		chain := h
+		h = func(ctx context.Context, call *Call) error {
+			err := chain(ctx, call)
+			if err == nil && !ran {
+				// There is no result (or rows) to return
+				return errNextNotCalled
+			}
+			return err
+		}
+	}
+
+	if db.Logger != nil && isQuery {
+		// Logging is outermost so that the duration includes the interceptors
+		return db.logQuery(ctx, call, h)
+	}
+	return h(ctx, call)
+}
@@ -0,0 +1,207 @@
+package sql_test
+
+import (
+	"context"
+	stdSql "database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+// chain records the order in which the logger, an interceptor and the tracer
+// see each query.
+type chain struct {
+	events []string
+}
+
+func (c *chain) Enabled(ctx context.Context, level sql.LogLevel) bool { return true }
+
+func (c *chain) Log(ctx context.Context, level sql.LogLevel, msg string, attrs ...sql.LogAttr) {
+	if msg != "query started" {
+		return
+	}
+	for _, attr := range attrs {
+		if attr.Key == "query" {
+			c.events = append(c.events, "log: "+attr.Value.(string))
+		}
+	}
+}
+
+func (c *chain) intercept(ctx context.Context, call *sql.Call, next sql.Handler) error {
+	c.events = append(c.events, "user: "+call.Query)
+	if !call.Stmt {
+		// Statements are rewritten when they are prepared
+		call.Query += " /* user */"
+	}
+	return next(ctx, call)
+}
+
+func (c *chain) Start(ctx context.Context, name string) (context.Context, sql.Span) {
+	return ctx, chainSpan{c}
+}
+
+type chainSpan struct {
+	c *chain
+}
+
+func (s chainSpan) SetAttribute(key string, value interface{}) {
+	if key == "db.statement" {
+		s.c.events = append(s.c.events, "trace: "+value.(string))
+	}
+}
+
+func (s chainSpan) RecordError(err error) {}
+
+func (s chainSpan) End() {}
+
+func TestInterceptorChain(t *testing.T) {
+	ctx := context.Background()
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT name`).WillReturnRows([]string{"name"}, []interface{}{"alice"})
+
+	c := &chain{}
+	pool := rec.DB()
+	pool.Logger = c
+	pool.Interceptors = []sql.Interceptor{c.intercept}
+	pool.Tracer = c
+	defer pool.Close()
+
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	expected := []string{
+		"log: UPDATE users SET active = 1",
+		"user: UPDATE users SET active = 1",
+		"trace: UPDATE users SET active = ? /* user */",
+	}
+
+	t.Run("Conn", func(t *testing.T) {
+		c.events = nil
+		_, err := conn.ExecContext(ctx, "UPDATE users SET active = 1")
+		require.NoError(t, err)
+		assert.Equal(t, expected, c.events)
+	})
+
+	t.Run("Tx", func(t *testing.T) {
+		tx, err := conn.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		c.events = nil
+		_, err = tx.ExecContext(ctx, "UPDATE users SET active = 1")
+		require.NoError(t, err)
+		assert.Equal(t, expected, c.events)
+	})
+
+	t.Run("Stmt", func(t *testing.T) {
+		stmt, err := conn.PrepareContext(ctx, "UPDATE users SET active = 1")
+		require.NoError(t, err)
+		defer stmt.Close()
+
+		c.events = nil
+		_, err = stmt.ExecContext(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"log: UPDATE users SET active = 1 /* user */",
+			"user: UPDATE users SET active = 1 /* user */",
+			"trace: UPDATE users SET active = ? /* user */",
+		}, c.events)
+	})
+
+	t.Run("Row", func(t *testing.T) {
+		c.events = nil
+		var name string
+		require.NoError(t, conn.QueryRowContext(ctx, "SELECT name FROM users WHERE id = 5").Scan(&name))
+		assert.Equal(t, "alice", name)
+		assert.Equal(t, []string{
+			"log: SELECT name FROM users WHERE id = 5",
+			"user: SELECT name FROM users WHERE id = 5",
+			"trace: SELECT name FROM users WHERE id = ? /* user */",
+		}, c.events)
+	})
+
+	// The rewritten queries reach the driver
+	for _, stmt := range rec.Statements() {
+		if strings.HasPrefix(stmt.Query, "UPDATE") || strings.HasPrefix(stmt.Query, "SELECT name") {
+			assert.Contains(t, stmt.Query, "/* user */")
+		}
+	}
+}
+
+func TestInterceptorNextNotCalled(t *testing.T) {
+	ctx := context.Background()
+	rec := sqltest.NewRecorder()
+
+	pool := rec.DB()
+	pool.Interceptors = []sql.Interceptor{
+		func(ctx context.Context, call *sql.Call, next sql.Handler) error {
+			if call.Op == sql.OpExec || call.Op == sql.OpQuery || call.Op == sql.OpQueryRow {
+				return nil
+			}
+			return next(ctx, call)
+		},
+	}
+	defer pool.Close()
+
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "UPDATE users SET active = 1")
+	assert.EqualError(t, err, "sql: interceptor did not call next")
+
+	rows, err := conn.QueryContext(ctx, "SELECT name FROM users")
+	assert.EqualError(t, err, "sql: interceptor did not call next")
+	assert.Nil(t, rows)
+
+	var name string
+	assert.EqualError(t, conn.QueryRowContext(ctx, "SELECT name FROM users").Scan(&name), "sql: interceptor did not call next")
+}
+
+func TestInterceptorVerifyKill(t *testing.T) {
+	srv, err := sqltest.NewServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.Handle(`UPDATE accounts`, sqltest.Response{Delay: 10 * time.Second})
+
+	std, err := stdSql.Open("mysql", srv.DSN())
+	require.NoError(t, err)
+	killer, err := stdSql.Open("mysql", srv.DSN())
+	require.NoError(t, err)
+
+	pool := &sql.DB{DB: std, KillerPool: killer, VerifyKill: true}
+	defer pool.Close()
+
+	// The interceptor prepends its own comment to the tagged query
+	pool.Interceptors = []sql.Interceptor{func(ctx context.Context, call *sql.Call, next sql.Handler) error {
+		call.Query = "/* rewritten */ " + call.Query
+		return next(ctx, call)
+	}}
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = conn.ExecContext(ctx, "UPDATE accounts SET balance = 0")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	var killed bool
+	for _, q := range srv.Queries() {
+		if strings.HasPrefix(q, "KILL QUERY") {
+			killed = true
+		}
+	}
+	assert.True(t, killed)
+}
@@ -0,0 +1,75 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterceptorOrder(t *testing.T) {
+	var order []string
+
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, call *Call, next Handler) error {
+			order = append(order, name+" before")
+			call.Query = call.Query + " " + name
+			err := next(ctx, call)
+			order = append(order, name+" after")
+			return err
+		}
+	}
+
+	db := &DB{Interceptors: []Interceptor{record("a"), record("b")}}
+
+	call := &Call{Op: OpExec, Query: "SELECT"}
+	err := db.intercept(context.Background(), call, func(ctx context.Context, call *Call) error {
+		order = append(order, "final: "+call.Query)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a before", "b before", "final: SELECT a b", "b after", "a after"}, order)
+}
+
+func TestInterceptorDeny(t *testing.T) {
+	errDenied := errors.New("denied")
+
+	db := &DB{Interceptors: []Interceptor{
+		func(ctx context.Context, call *Call, next Handler) error {
+			if call.Op == OpExec {
+				return errDenied
+			}
+			return next(ctx, call)
+		},
+	}}
+
+	ran := false
+	final := func(ctx context.Context, call *Call) error {
+		ran = true
+		return nil
+	}
+
+	assert.Equal(t, errDenied, db.intercept(context.Background(), &Call{Op: OpExec}, final))
+	assert.False(t, ran)
+
+	assert.NoError(t, db.intercept(context.Background(), &Call{Op: OpQuery}, final))
+	assert.True(t, ran)
+}
+
+func TestInterceptorNextNotCalled(t *testing.T) {
+	db := &DB{Interceptors: []Interceptor{
+		func(ctx context.Context, call *Call, next Handler) error {
+			return nil
+		},
+	}}
+
+	ran := false
+	final := func(ctx context.Context, call *Call) error {
+		ran = true
+		return nil
+	}
+
+	assert.Equal(t, errNextNotCalled, db.intercept(context.Background(), &Call{Op: OpExec}, final))
+	assert.False(t, ran)
+}
@@ -56,7 +56,20 @@ type StdSQLTx interface {
 	Rollback() error
 }
 
-// SQLBasic is the interface that allows Conn and Stmt to be used.
+// SQLDB is the interface that allows DB to be used.
+// Unlike DB.Conn, SQLConn returns the SQLMockableConn interface so that DB can be
+// substituted with a mock.
+type SQLDB interface {
+	StdSQLCommon
+	Ping() error
+	PingContext(ctx context.Context) error
+	Begin() (*stdSql.Tx, error)
+	BeginTx(ctx context.Context, opts *stdSql.TxOptions) (*stdSql.Tx, error)
+	SQLConn(ctx context.Context) (SQLMockableConn, error)
+	Close() error
+}
+
+// SQLBasic is the interface that allows Conn and Tx to be used.
 type SQLBasic interface {
 	ExecContext(ctx context.Context, query string, args ...interface{}) (stdSql.Result, error)
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error)
@@ -72,6 +85,25 @@ type SQLConn interface {
 	PrepareContext(ctx context.Context, query string) (*Stmt, error)
 }
 
+// SQLMockableConn is the same as SQLConn except that transactions and prepared
+// statements are returned as interfaces so that it can be implemented by a mock.
+// Use DB.SQLConn to obtain a Conn as a SQLMockableConn.
+type SQLMockableConn interface {
+	SQLBasic
+	BeginTx(ctx context.Context, opts *stdSql.TxOptions) (SQLTx, error)
+	Close() error
+	PingContext(ctx context.Context) error
+	PrepareContext(ctx context.Context, query string) (SQLStmt, error)
+}
+
+// SQLStmt is the interface that allows Stmt to be used.
+type SQLStmt interface {
+	ExecContext(ctx context.Context, args ...interface{}) (stdSql.Result, error)
+	QueryContext(ctx context.Context, args ...interface{}) (*Rows, error)
+	QueryRowContext(ctx context.Context, args ...interface{}) *Row
+	Close() error
+}
+
 // SQLTx is the interface that allows Tx to be used.
 type SQLTx interface {
 	SQLBasic
@@ -80,3 +112,22 @@ type SQLTx interface {
 	Commit() error
 	Rollback() error
 }
+
+// SQLNestableTx is the interface that allows Tx and NestedTx to be used.
+// Library code that accepts a SQLNestableTx can start a "sub-transaction"
+// without knowing whether it was given the outer transaction or a nested one.
+type SQLNestableTx interface {
+	SQLTx
+	BeginNested(ctx context.Context) (*NestedTx, error)
+}
+
+var (
+	_ SQLDB           = (*DB)(nil)
+	_ SQLBasic        = (*Conn)(nil)
+	_ SQLConn         = (*Conn)(nil)
+	_ SQLMockableConn = sqlConn{}
+	_ SQLTx           = (*Tx)(nil)
+	_ SQLStmt         = (*Stmt)(nil)
+	_ SQLNestableTx   = (*Tx)(nil)
+	_ SQLNestableTx   = (*NestedTx)(nil)
+)
@@ -0,0 +1,77 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"iter"
+)
+
+// All returns an iterator over the rows. The Rows are closed when the
+// iteration ends. If the loop is exited early (using break or return), a KILL
+// QUERY signal is sent before the Rows are closed. This happens on every early
+// exit, even after the last row, since whether the result set has been read is
+// only known once Next returns false. If an error is encountered during
+// iteration, it is yielded last.
+//
+// Example:
+//
+//	for row, err := range rows.All() {
+//	   if err != nil {
+//	      return err
+//	   }
+//	   err = row.Scan(&id, &name)
+//	}
+func (rs *Rows) All() iter.Seq2[*Rows, error] {
+	return func(yield func(*Rows, error) bool) {
+		defer rs.Close()
+
+		for rs.Next() {
+			if !yield(rs, nil) {
+				rs.Kill()
+				return
+			}
+		}
+		if err := rs.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// All returns an iterator over the rows scanned into a T. See QueryAll for how
+// the columns are mapped to T and Rows.All for how the Rows are closed.
+// Iteration stops after an error is yielded.
+//
+// Example:
+//
+//	for user, err := range sql.All[User](rows) {
+//	   ...
+//	}
+func All[T any](rs *Rows) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer rs.Close()
+
+		var zero T
+
+		cols, err := rs.Columns()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		for rs.Next() {
+			var v T
+			if err := scanRow(rs, &v, cols); err != nil {
+				rs.Kill()
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				rs.Kill()
+				return
+			}
+		}
+		if err := rs.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
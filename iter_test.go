@@ -0,0 +1,159 @@
+package sql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+func TestRowsAll(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT id, name`).WillReturnRows([]string{"id", "name"},
+		[]interface{}{1, "alice"},
+		[]interface{}{2, "bob"},
+		[]interface{}{3, "carol"},
+	)
+
+	pool := rec.DB()
+	pool.TrackHandles = true
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+
+	var connectionID string
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&connectionID))
+
+	// Complete iteration
+	rows, err := conn.QueryContext(ctx, "SELECT id, name FROM users")
+	require.NoError(t, err)
+
+	var names []string
+	for row, err := range rows.All() {
+		require.NoError(t, err)
+		var (
+			id   int
+			name string
+		)
+		require.NoError(t, row.Scan(&id, &name))
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"alice", "bob", "carol"}, names)
+	assert.False(t, rec.Killed(connectionID))
+
+	// Early break
+	rows, err = conn.QueryContext(ctx, "SELECT id, name FROM users")
+	require.NoError(t, err)
+
+	for range rows.All() {
+		break
+	}
+	assert.True(t, rec.Killed(connectionID))
+
+	// Breaking on the last row still kills
+	rows, err = conn.QueryContext(ctx, "SELECT id, name FROM users")
+	require.NoError(t, err)
+
+	for row := range rows.All() {
+		var (
+			id   int
+			name string
+		)
+		require.NoError(t, row.Scan(&id, &name))
+		if name == "carol" {
+			break
+		}
+	}
+	assert.Len(t, rec.Kills(), 2)
+
+	require.NoError(t, conn.Close())
+	sqltest.VerifyNoLeaks(t, pool)
+}
+
+func TestAll(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT id, name`).WillReturnRows([]string{"id", "name"},
+		[]interface{}{1, "alice"},
+		[]interface{}{2, "bob"},
+	)
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, "SELECT id, name FROM users")
+	require.NoError(t, err)
+
+	var users []user
+	for u, err := range sql.All[user](rows) {
+		require.NoError(t, err)
+		users = append(users, u)
+	}
+	assert.Equal(t, []user{{ID: 1, Name: "alice"}, {ID: 2, Name: "bob"}}, users)
+	assert.Empty(t, rec.Kills())
+
+	rows, err = conn.QueryContext(ctx, "SELECT id, name FROM users")
+	require.NoError(t, err)
+
+	var errs []error
+	for _, err := range sql.All[int](rows) {
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "sql: expected 1 column for int, not 2")
+}
+
+func TestRowsAllBreakNotReportedAsKilled(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT id, name`).WillReturnRows([]string{"id", "name"},
+		[]interface{}{1, "alice"},
+		[]interface{}{2, "bob"},
+	)
+
+	var reported []sql.SlowQuery
+	slowLog := &sql.SlowQueryLog{
+		Threshold: time.Hour,
+		Report:    func(q sql.SlowQuery) { reported = append(reported, q) },
+	}
+	tracer := &sqltest.TracerRecorder{}
+
+	pool := rec.DB()
+	pool.Interceptors = []sql.Interceptor{slowLog.Intercept}
+	pool.Tracer = tracer
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var connectionID string
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&connectionID))
+
+	rows, err := conn.QueryContext(ctx, "SELECT id, name FROM users")
+	require.NoError(t, err)
+
+	for range rows.All() {
+		break
+	}
+
+	// The KILL is sent but the query was not canceled
+	assert.True(t, rec.Killed(connectionID))
+	assert.Empty(t, reported)
+	assert.Zero(t, pool.CancelStats().KillsAttempted)
+	for _, s := range tracer.Spans() {
+		assert.NotContains(t, s.Attributes, "db.mysql.killed", s.Name)
+		assert.NotEqual(t, "mysql KILL QUERY", s.Name)
+	}
+}
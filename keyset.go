@@ -0,0 +1,255 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultKeysetBatchSize is the number of rows in each chunk if Keyset.BatchSize is not set.
+const DefaultKeysetBatchSize = 1000
+
+// Keyset iterates over a large table in chunks using keyset pagination:
+//
+//	SELECT ... FROM table WHERE (key) > (?) ORDER BY key LIMIT n
+//
+// Unlike OFFSET pagination, each chunk is read using the key's index. Unlike a
+// single large SELECT, the connection (and the InnoDB read view) is only held while a chunk
+// is read, so a chunk can be processed without holding up the database.
+//
+// Example:
+//
+//	ks := sql.Keyset{Table: "orders", Columns: []string{"id", "total"}, Key: []string{"id"}, Throttle: 100 * time.Millisecond}
+//	err := ks.Each(ctx, conn, func(b *sql.KeysetBatch) error {
+//	   for _, row := range b.Rows {
+//	      ...
+//	   }
+//	   return nil
+//	})
+type Keyset struct {
+
+	// Database is the database containing Table.
+	// If empty, the connection's default database is used.
+	Database string
+
+	// Table is the table to read.
+	Table string
+
+	// Columns are the columns to read. If empty, every column is read.
+	// The key columns must be included.
+	Columns []string
+
+	// Key are the columns (usually the primary key) that the rows are ordered by.
+	// They must uniquely identify a row.
+	Key []string
+
+	// Where is an optional condition that the rows must also satisfy, with
+	// placeholders for Args. For example: "status = ?".
+	Where string
+	Args  []interface{}
+
+	// After are the values of the key to start after (for example, to resume an
+	// earlier iteration). If empty, iteration starts from the first row.
+	After []interface{}
+
+	// BatchSize is the maximum number of rows in each chunk.
+	// A value of zero defaults to DefaultKeysetBatchSize.
+	BatchSize int
+
+	// ChunkTimeout is how long each chunk's query can run for before it is canceled
+	// (and killed). A value of zero is equivalent to no time limit.
+	ChunkTimeout time.Duration
+
+	// Throttle is how long to wait between chunks.
+	Throttle time.Duration
+}
+
+// KeysetBatch is a chunk of rows read by Keyset.
+type KeysetBatch struct {
+	Columns []string
+
+	// Rows are the values of each row, converted using Rows.ScanSlice.
+	Rows [][]interface{}
+
+	// Last are the values of the key of the last row.
+	// They can be used as Keyset.After to resume iteration.
+	Last []interface{}
+}
+
+// Each reads the rows in chunks and calls fn with each chunk, in the order of
+// the key. Each chunk is read entirely (and its query finished) before fn is called.
+// If fn returns an error, iteration stops and the error is returned.
+func (k Keyset) Each(ctx context.Context, q SQLBasic, fn func(b *KeysetBatch) error) error {
+
+	if k.Table == "" || len(k.Key) == 0 {
+		return errors.New("sql: Keyset requires Table and Key")
+	}
+	if len(k.After) > 0 && len(k.After) != len(k.Key) {
+		return fmt.Errorf("sql: Keyset expected %d values for After, not %d", len(k.Key), len(k.After))
+	}
+
+	size := k.BatchSize
+	if size <= 0 {
+		size = DefaultKeysetBatchSize
+	}
+
+	after := k.After
+	for first := true; ; first = false {
+		if !first && k.Throttle > 0 {
+			timer := time.NewTimer(k.Throttle)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		}
+
+		b, err := k.chunk(ctx, q, after, size)
+		if err != nil {
+			return err
+		}
+		if len(b.Rows) == 0 {
+			return nil
+		}
+
+		if err := fn(b); err != nil {
+			return err
+		}
+
+		if len(b.Rows) < size {
+			return nil
+		}
+		after = b.Last
+	}
+}
+
+// chunk reads the rows that follow the key values after.
+func (k Keyset) chunk(ctx context.Context, q SQLBasic, after []interface{}, size int) (*KeysetBatch, error) {
+
+	var cancel context.CancelFunc
+	if k.ChunkTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, k.ChunkTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	query, args := k.query(after, size)
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	keyIdx, err := k.keyIndexes(cols)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &KeysetBatch{Columns: cols, Rows: make([][]interface{}, 0, size)}
+	for rows.Next() {
+		vals, err := rows.ScanSlice()
+		if err != nil {
+			return nil, err
+		}
+		b.Rows = append(b.Rows, vals)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	if len(b.Rows) > 0 {
+		last := b.Rows[len(b.Rows)-1]
+		b.Last = make([]interface{}, len(keyIdx))
+		for i, idx := range keyIdx {
+			b.Last[i] = last[idx]
+		}
+	}
+	return b, nil
+}
+
+// query returns the query (and its arguments) that reads the chunk following after.
+func (k Keyset) query(after []interface{}, size int) (string, []interface{}) {
+
+	var (
+		b    strings.Builder
+		args []interface{}
+	)
+
+	b.WriteString("SELECT ")
+	if len(k.Columns) == 0 {
+		b.WriteString("*")
+	} else {
+		b.WriteString(quoteIdentifiers(k.Columns))
+	}
+
+	b.WriteString(" FROM ")
+	if k.Database != "" {
+		b.WriteString(quoteIdentifier(k.Database))
+		b.WriteString(".")
+	}
+	b.WriteString(quoteIdentifier(k.Table))
+
+	var conds []string
+	if k.Where != "" {
+		conds = append(conds, "("+k.Where+")")
+		args = append(args, k.Args...)
+	}
+	if len(after) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(after)), ", ")
+		conds = append(conds, "("+quoteIdentifiers(k.Key)+") > ("+placeholders+")")
+		args = append(args, after...)
+	}
+	if len(conds) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(conds, " AND "))
+	}
+
+	b.WriteString(" ORDER BY ")
+	b.WriteString(quoteIdentifiers(k.Key))
+	b.WriteString(" LIMIT ")
+	b.WriteString(strconv.Itoa(size))
+
+	return b.String(), args
+}
+
+// keyIndexes returns the position of each key column in cols.
+func (k Keyset) keyIndexes(cols []string) ([]int, error) {
+	idx := make([]int, len(k.Key))
+
+keys:
+	for i, key := range k.Key {
+		for j, col := range cols {
+			if strings.EqualFold(key, col) {
+				idx[i] = j
+				continue keys
+			}
+		}
+		return nil, fmt.Errorf("sql: Keyset key column %q is not selected", key)
+	}
+	return idx, nil
+}
+
+// quoteIdentifiers quotes and joins a list of column names.
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,140 @@
+package sql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+func TestKeyset(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`> \(\?, \?\)`).WillReturnRows([]string{"tenant", "id", "name"},
+		[]interface{}{1, 3, "carol"},
+	)
+	rec.Expect("^SELECT `tenant`").WillReturnRows([]string{"tenant", "id", "name"},
+		[]interface{}{1, 1, "alice"},
+		[]interface{}{1, 2, "bob"},
+	)
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ks := sql.Keyset{
+		Database:  "app",
+		Table:     "users",
+		Columns:   []string{"tenant", "id", "name"},
+		Key:       []string{"tenant", "id"},
+		Where:     "active = ?",
+		Args:      []interface{}{true},
+		BatchSize: 2,
+		Throttle:  time.Millisecond,
+	}
+
+	var (
+		names []string
+		lasts [][]interface{}
+	)
+	err = ks.Each(ctx, conn, func(b *sql.KeysetBatch) error {
+		for _, row := range b.Rows {
+			names = append(names, row[2].(string))
+		}
+		lasts = append(lasts, b.Last)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob", "carol"}, names)
+	assert.Equal(t, [][]interface{}{{int64(1), int64(2)}, {int64(1), int64(3)}}, lasts)
+
+	stmts := rec.Statements()
+	require.Len(t, stmts, 3)
+	assert.Equal(t, "SELECT `tenant`, `id`, `name` FROM `app`.`users` WHERE (active = ?) ORDER BY `tenant`, `id` LIMIT 2", stmts[1].Query)
+	assert.Equal(t, "SELECT `tenant`, `id`, `name` FROM `app`.`users` WHERE (active = ?) AND (`tenant`, `id`) > (?, ?) ORDER BY `tenant`, `id` LIMIT 2", stmts[2].Query)
+	assert.Len(t, stmts[2].Args, 3)
+}
+
+func TestKeysetStop(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT`).WillReturnRows([]string{"id"}, []interface{}{1})
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stop := errors.New("stop")
+	err = sql.Keyset{Table: "users", Key: []string{"id"}, BatchSize: 1}.Each(ctx, conn, func(b *sql.KeysetBatch) error {
+		return stop
+	})
+	assert.Equal(t, stop, err)
+
+	err = sql.Keyset{Table: "users", Key: []string{"uuid"}}.Each(ctx, conn, func(b *sql.KeysetBatch) error {
+		return nil
+	})
+	assert.EqualError(t, err, `sql: Keyset key column "uuid" is not selected`)
+}
+
+func TestKeysetChunkTimeout(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT`).WillDelayFor(10 * time.Second)
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var connectionID string
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&connectionID))
+
+	start := time.Now()
+	err = sql.Keyset{Table: "users", Key: []string{"id"}, ChunkTimeout: 50 * time.Millisecond}.Each(ctx, conn, func(b *sql.KeysetBatch) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.True(t, rec.Killed(connectionID))
+}
+
+func TestKeysetThrottleCancel(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT`).WillReturnRows([]string{"id"}, []interface{}{1})
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var batches int
+	start := time.Now()
+	err = sql.Keyset{Table: "users", Key: []string{"id"}, BatchSize: 1, Throttle: time.Hour}.Each(ctx, conn, func(b *sql.KeysetBatch) error {
+		batches++
+		cancel()
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, batches)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Empty(t, rec.Kills())
+}
@@ -0,0 +1,181 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Reasons a Leak is reported.
+const (
+	LeakNotClosed    = "not closed"
+	LeakNotUnleaked  = "not unleaked"
+	LeakStillRunning = "still running"
+)
+
+// Leak describes a Conn, Tx, Stmt or Rows that was not released, or a goroutine
+// started by ExecContext that is still running. See DB.Leaks.
+type Leak struct {
+	Kind         string // Conn, Tx, Stmt, Rows or goroutine
+	Reason       string // LeakNotClosed, LeakNotUnleaked or LeakStillRunning
+	ConnectionID string
+	Query        string
+	Stack        []byte // Stack of the goroutine that obtained the handle
+}
+
+// String returns a description of the leak (excluding the stack).
+func (l Leak) String() string {
+	s := fmt.Sprintf("%s %s (connection_id: %s)", l.Kind, l.Reason, l.ConnectionID)
+	if l.Query != "" {
+		s += fmt.Sprintf(" query: %q", l.Query)
+	}
+	return s
+}
+
+// trackedHandle is a Conn, Tx, Stmt, Rows or goroutine tracked by a DB.
+// It does not refer to the handle so that a leaked handle can still be
+// garbage collected (and reported by its finalizer).
+type trackedHandle struct {
+	seq          uint64
+	kind         string
+	connectionID string
+	query        string
+	stack        []byte
+
+	// Set atomically when the handle is closed and unleaked
+	closed   int32
+	unleaked int32
+}
+
+// handleTracker records the handles obtained from a DB when TrackHandles is set.
+type handleTracker struct {
+	lock    sync.Mutex
+	seq     uint64
+	handles map[*trackedHandle]struct{}
+}
+
+// track starts tracking a handle. It returns nil if TrackHandles is not set.
+// unleaked must be set if the handle has no reference to a KillerPool to release.
+func (db *DB) track(kind, connectionID, query string, unleaked bool) *trackedHandle {
+	if db == nil || !db.TrackHandles {
+		return nil
+	}
+
+	h := &trackedHandle{kind: kind, connectionID: connectionID, query: query, stack: debug.Stack()}
+	if unleaked {
+		h.unleaked = 1
+	}
+
+	db.handles.lock.Lock()
+	defer db.handles.lock.Unlock()
+
+	if db.handles.handles == nil {
+		db.handles.handles = map[*trackedHandle]struct{}{}
+	}
+	db.handles.seq++
+	h.seq = db.handles.seq
+	db.handles.handles[h] = struct{}{}
+	return h
+}
+
+// trackGoroutine starts tracking a goroutine started by ExecContext.
+// untrack must be called when the goroutine exits.
+func (db *DB) trackGoroutine(connectionID, query string) *trackedHandle {
+	return db.track("goroutine", connectionID, query, true)
+}
+
+// untrack stops tracking a handle.
+func (db *DB) untrack(h *trackedHandle) {
+	if h == nil {
+		return
+	}
+	db.handles.lock.Lock()
+	delete(db.handles.handles, h)
+	db.handles.lock.Unlock()
+}
+
+// trackConn starts tracking a Conn.
+func (db *DB) trackConn(c *Conn) {
+	c.tracked = db.track("Conn", c.connectionID, "", c.killerPool == nil)
+}
+
+// trackTx starts tracking a Tx.
+func (db *DB) trackTx(tx *Tx) {
+	tx.tracked = db.track("Tx", tx.connectionID, "", tx.killerPool == nil)
+}
+
+// trackStmt starts tracking a Stmt.
+func (db *DB) trackStmt(s *Stmt) {
+	s.tracked = db.track("Stmt", s.connectionID, s.query, s.killerPool == nil)
+}
+
+// trackRows starts tracking a Rows.
+func (db *DB) trackRows(rs *Rows) {
+	rs.tracked = db.track("Rows", rs.connectionID, rs.query, rs.killerPool == nil)
+}
+
+// markClosed records that the handle has been closed.
+func (h *trackedHandle) markClosed() {
+	if h != nil {
+		atomic.StoreInt32(&h.closed, 1)
+	}
+}
+
+// markUnleaked records that the handle has been unleaked.
+func (h *trackedHandle) markUnleaked() {
+	if h != nil {
+		atomic.StoreInt32(&h.unleaked, 1)
+	}
+}
+
+// reason returns why the handle has not been released or an empty string.
+func (h *trackedHandle) reason() string {
+	switch {
+	case h.kind == "goroutine":
+		return LeakStillRunning
+	case atomic.LoadInt32(&h.closed) == 0:
+		return LeakNotClosed
+	case atomic.LoadInt32(&h.unleaked) == 0:
+		return LeakNotUnleaked
+	}
+	return ""
+}
+
+// Leaks returns the Conn, Tx, Stmt and Rows obtained from the DB that have not been
+// closed, or were closed without being unleaked (for example, because Close returned
+// an error). It also returns the goroutines started by ExecContext that are still running.
+// Leaks are returned in the order the handles were obtained.
+//
+// Handles are only tracked if TrackHandles is set. See sqltest.VerifyNoLeaks.
+func (db *DB) Leaks() []Leak {
+	db.handles.lock.Lock()
+	handles := make([]*trackedHandle, 0, len(db.handles.handles))
+	for h := range db.handles.handles {
+		handles = append(handles, h)
+	}
+	db.handles.lock.Unlock()
+
+	sort.Slice(handles, func(i, j int) bool { return handles[i].seq < handles[j].seq })
+
+	var leaks []Leak
+	for _, h := range handles {
+		reason := h.reason()
+		if reason == "" {
+			// Released, so there is no need to track it further
+			db.untrack(h)
+			continue
+		}
+		leaks = append(leaks, Leak{
+			Kind:         h.kind,
+			Reason:       reason,
+			ConnectionID: h.connectionID,
+			Query:        h.query,
+			Stack:        h.stack,
+		})
+	}
+	return leaks
+}
@@ -0,0 +1,145 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// LogLevel is the severity of a log record.
+// The values match the levels of log/slog.
+type LogLevel int
+
+// Log levels.
+const (
+	LogDebug LogLevel = -4
+	LogInfo  LogLevel = 0
+	LogWarn  LogLevel = 4
+	LogError LogLevel = 8
+)
+
+// String returns the name of the level.
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "DEBUG"
+	case LogInfo:
+		return "INFO"
+	case LogWarn:
+		return "WARN"
+	case LogError:
+		return "ERROR"
+	}
+	return "UNKNOWN"
+}
+
+// LogAttr is a key-value pair attached to a log record.
+type LogAttr struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger receives structured log records. It is modeled on log/slog's Handler.
+// See NewSlogLogger.
+//
+// The following records are logged:
+//
+//	DEBUG  query started, query finished
+//	INFO   query canceled, query killed, connection killed, kill skipped
+//	WARN   leaked Conn, leaked Tx, leaked Stmt, leaked Rows
+//	ERROR  query failed, kill failed, rollback failed
+type Logger interface {
+	// Enabled reports whether records with the given level are logged.
+	Enabled(ctx context.Context, level LogLevel) bool
+
+	// Log logs a record.
+	Log(ctx context.Context, level LogLevel, msg string, attrs ...LogAttr)
+}
+
+// Keys of the attributes that are logged.
+const (
+	logKeyOp           = "op"
+	logKeyQuery        = "query"
+	logKeyArgs         = "args"
+	logKeyConnectionID = "connection_id"
+	logKeyDuration     = "duration"
+	logKeyKilled       = "killed"
+	logKeyError        = "error"
+)
+
+// log logs a record if a Logger is set and the level is enabled.
+func (db *DB) log(ctx context.Context, level LogLevel, msg string, attrs ...LogAttr) {
+	if db == nil || db.Logger == nil || !db.Logger.Enabled(ctx, level) {
+		return
+	}
+	db.Logger.Log(ctx, level, msg, attrs...)
+}
+
+// logQuery logs the start and finish of an operation.
+func (db *DB) logQuery(ctx context.Context, call *Call, next Handler) error {
+
+	db.log(ctx, LogDebug, "query started",
+		LogAttr{logKeyOp, call.Op.String()},
+		LogAttr{logKeyConnectionID, call.ConnectionID},
+		LogAttr{logKeyQuery, db.Redact.Query(call.Query)},
+		LogAttr{logKeyArgs, db.Redact.Args(call.Query, call.Args)},
+	)
+
+	start := time.Now()
+	err := next(ctx, call)
+	duration := time.Since(start)
+
+	attrs := []LogAttr{
+		{logKeyOp, call.Op.String()},
+		{logKeyConnectionID, call.ConnectionID},
+		{logKeyQuery, db.Redact.Query(call.Query)},
+		{logKeyDuration, duration},
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		attrs = append(attrs, LogAttr{logKeyKilled, call.Killed}, LogAttr{logKeyError, ctx.Err()})
+		db.log(ctx, LogInfo, "query canceled", attrs...)
+	case err != nil:
+		attrs = append(attrs, LogAttr{logKeyArgs, db.Redact.Args(call.Query, call.Args)}, LogAttr{logKeyError, err})
+		db.log(ctx, LogError, "query failed", attrs...)
+	default:
+		db.log(ctx, LogDebug, "query finished", attrs...)
+	}
+
+	return err
+}
+
+// leaked is set as the finalizer of a Conn when a Logger is set.
+func (c *Conn) leaked() {
+	if atomic.LoadInt32(&c.closed) == 0 {
+		c.db.log(context.Background(), LogWarn, "leaked Conn", LogAttr{logKeyConnectionID, c.connectionID})
+	}
+}
+
+// leaked is set as the finalizer of a Tx when a Logger or TxMonitor is set.
+// The TxMonitor stops tracking the leaked Tx.
+func (tx *Tx) leaked() {
+	if atomic.LoadInt32(&tx.closed) == 0 {
+		tx.db.log(context.Background(), LogWarn, "leaked Tx", LogAttr{logKeyConnectionID, tx.connectionID})
+		if tx.monitored != nil {
+			tx.monitored.untrack()
+		}
+	}
+}
+
+// leaked is set as the finalizer of a Stmt when a Logger is set.
+func (s *Stmt) leaked() {
+	if atomic.LoadInt32(&s.closed) == 0 {
+		s.db.log(context.Background(), LogWarn, "leaked Stmt", LogAttr{logKeyConnectionID, s.connectionID}, LogAttr{logKeyQuery, s.db.Redact.Query(s.query)})
+	}
+}
+
+// leaked is set as the finalizer of a Rows when a Logger is set.
+func (rs *Rows) leaked() {
+	if atomic.LoadInt32(&rs.closed) == 0 {
+		rs.db.log(context.Background(), LogWarn, "leaked Rows", LogAttr{logKeyConnectionID, rs.connectionID}, LogAttr{logKeyQuery, rs.db.Redact.Query(rs.query)})
+	}
+}
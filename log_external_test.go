@@ -0,0 +1,85 @@
+package sql_test
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+func TestLogRedactsQuery(t *testing.T) {
+	var buf syncBuffer
+	pool := sqltest.NewRecorder().DB()
+	pool.Logger = sql.NewSlogLogger(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	pool.Redact = &sql.Redaction{Columns: []string{"password"}}
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.ExecContext(context.Background(), "UPDATE users SET email = 'alice@example.com', password = ? WHERE id = 5", "hunter2")
+	require.NoError(t, err)
+
+	logged := buf.String()
+	assert.Contains(t, logged, `query="UPDATE users SET email = ?, password = ? WHERE id = ?"`)
+	assert.NotContains(t, logged, "alice@example.com")
+	assert.NotContains(t, logged, "hunter2")
+}
+
+func TestLogLeakedStmt(t *testing.T) {
+	var buf syncBuffer
+	pool := sqltest.NewRecorder().DB()
+	pool.Logger = sql.NewSlogLogger(slog.NewTextHandler(&buf, nil))
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	func() {
+		_, err := conn.PrepareContext(context.Background(), "SELECT name FROM users WHERE id = ?")
+		require.NoError(t, err)
+	}()
+
+	assert.Eventually(t, func() bool {
+		runtime.GC()
+		return strings.Contains(buf.String(), `msg="leaked Stmt"`)
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, buf.String(), `query="SELECT name FROM users WHERE id = ?"`)
+}
+
+func TestRowsClosedByNext(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT name`).WillReturnRows([]string{"name"}, []interface{}{"alice"})
+
+	pool := rec.DB()
+	pool.TrackHandles = true
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(context.Background(), "SELECT name FROM users")
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	require.NoError(t, rows.Err())
+
+	// Closed and released since there are no further result sets
+	assert.False(t, rows.Next())
+	assert.False(t, rows.NextResultSet())
+	assert.NoError(t, rows.Kill())
+	for _, leak := range pool.Leaks() {
+		assert.NotEqual(t, "Rows", leak.Kind, leak.String())
+	}
+}
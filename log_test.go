@@ -0,0 +1,63 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedLog struct {
+	level LogLevel
+	msg   string
+	attrs map[string]interface{}
+}
+
+type testLogger struct {
+	logs []recordedLog
+}
+
+func (l *testLogger) Enabled(ctx context.Context, level LogLevel) bool {
+	return true
+}
+
+func (l *testLogger) Log(ctx context.Context, level LogLevel, msg string, attrs ...LogAttr) {
+	rec := recordedLog{level: level, msg: msg, attrs: map[string]interface{}{}}
+	for _, a := range attrs {
+		rec.attrs[a.Key] = a.Value
+	}
+	l.logs = append(l.logs, rec)
+}
+
+func TestLogQuery(t *testing.T) {
+	logger := &testLogger{}
+	db := &DB{Logger: logger, Redact: &Redaction{Columns: []string{"password"}}}
+
+	errFailed := errors.New("failed")
+	db.intercept(context.Background(), &Call{Op: OpExec, Query: "UPDATE users SET password = ?", Args: []interface{}{"hunter2"}}, func(ctx context.Context, call *Call) error {
+		return errFailed
+	})
+
+	if assert.Len(t, logger.logs, 2) {
+		assert.Equal(t, "query started", logger.logs[0].msg)
+		assert.Equal(t, []interface{}{"[REDACTED]"}, logger.logs[0].attrs["args"])
+		assert.Equal(t, LogError, logger.logs[1].level)
+		assert.Equal(t, "query failed", logger.logs[1].msg)
+		assert.Equal(t, errFailed, logger.logs[1].attrs["error"])
+	}
+
+	logger.logs = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	db.intercept(ctx, &Call{Op: OpQuery, Query: "SELECT 1"}, func(ctx context.Context, call *Call) error {
+		call.Killed = true
+		return ctx.Err()
+	})
+
+	if assert.Len(t, logger.logs, 2) {
+		assert.Equal(t, LogInfo, logger.logs[1].level)
+		assert.Equal(t, "query canceled", logger.logs[1].msg)
+		assert.Equal(t, true, logger.logs[1].attrs["killed"])
+	}
+}
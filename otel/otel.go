@@ -0,0 +1,61 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+// Package otel adapts an OpenTelemetry tracer for use as a
+// github.com/rocketlaunchr/mysql-go Tracer.
+//
+//	pool.Tracer = otel.NewTracer(tracerProvider.Tracer("mysql"))
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracer returns a sql.Tracer that creates OpenTelemetry client spans using t.
+func NewTracer(t trace.Tracer) sql.Tracer {
+	return tracer{t}
+}
+
+type tracer struct {
+	t trace.Tracer
+}
+
+func (t tracer) Start(ctx context.Context, name string) (context.Context, sql.Span) {
+	ctx, s := t.t.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, span{s}
+}
+
+type span struct {
+	s trace.Span
+}
+
+func (s span) SetAttribute(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		s.s.SetAttributes(attribute.String(key, v))
+	case bool:
+		s.s.SetAttributes(attribute.Bool(key, v))
+	case int:
+		s.s.SetAttributes(attribute.Int(key, v))
+	case int64:
+		s.s.SetAttributes(attribute.Int64(key, v))
+	case float64:
+		s.s.SetAttributes(attribute.Float64(key, v))
+	default:
+		s.s.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+}
+
+func (s span) RecordError(err error) {
+	s.s.RecordError(err)
+	s.s.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.s.End()
+}
@@ -0,0 +1,58 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	tracer := NewTracer(tp.Tracer("mysql"))
+
+	ctx, parent := tracer.Start(context.Background(), "mysql Exec")
+	parent.SetAttribute("db.statement", "UPDATE users SET active = ?")
+	parent.SetAttribute("db.mysql.killed", true)
+	parent.SetAttribute("db.rows_affected", int64(3))
+	parent.SetAttribute("count", 2)
+	parent.SetAttribute("db.mysql.kill_latency_ms", 1.5)
+	parent.SetAttribute("other", struct{ A int }{1})
+
+	_, child := tracer.Start(ctx, "mysql KILL QUERY")
+	child.RecordError(errors.New("kill failed"))
+	child.End()
+	parent.End()
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+
+	kill, exec := spans[0], spans[1]
+
+	assert.Equal(t, "mysql Exec", exec.Name())
+	assert.Equal(t, trace.SpanKindClient, exec.SpanKind())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("db.statement", "UPDATE users SET active = ?"),
+		attribute.Bool("db.mysql.killed", true),
+		attribute.Int64("db.rows_affected", 3),
+		attribute.Int("count", 2),
+		attribute.Float64("db.mysql.kill_latency_ms", 1.5),
+		attribute.String("other", "{1}"),
+	}, exec.Attributes())
+	assert.Equal(t, codes.Unset, exec.Status().Code)
+
+	assert.Equal(t, "mysql KILL QUERY", kill.Name())
+	assert.Equal(t, exec.SpanContext().SpanID(), kill.Parent().SpanID())
+	assert.Equal(t, codes.Error, kill.Status().Code)
+	assert.Equal(t, "kill failed", kill.Status().Description)
+	require.Len(t, kill.Events(), 1)
+	assert.Equal(t, "exception", kill.Events()[0].Name)
+}
@@ -0,0 +1,100 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+// Package prom exports the CancelStats of a github.com/rocketlaunchr/mysql-go DB
+// as Prometheus metrics.
+//
+//	prometheus.MustRegister(prom.NewCollector(pool, "myapp"))
+package prom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	sql "github.com/rocketlaunchr/mysql-go"
+)
+
+// NewCollector returns a prometheus.Collector that reports db.CancelStats().
+// namespace is prepended to the name of every metric and may be empty.
+func NewCollector(db *sql.DB, namespace string) prometheus.Collector {
+	name := func(n string) string {
+		return prometheus.BuildFQName(namespace, "mysql", n)
+	}
+
+	return &collector{
+		db: db,
+
+		queriesStarted:   prometheus.NewDesc(name("queries_started_total"), "Number of queries started.", nil, nil),
+		queriesFinished:  prometheus.NewDesc(name("queries_finished_total"), "Number of queries finished.", nil, nil),
+		contextsCanceled: prometheus.NewDesc(name("contexts_canceled_total"), "Number of queries that finished with a canceled context.", nil, nil),
+		killsAttempted:   prometheus.NewDesc(name("kills_attempted_total"), "Number of KILL signals attempted.", nil, nil),
+		kills:            prometheus.NewDesc(name("kills_total"), "Number of KILL signals by outcome.", []string{"outcome"}, nil),
+		killsSkipped:     prometheus.NewDesc(name("kills_skipped_total"), "Number of KILL signals not sent because the query had already finished.", nil, nil),
+		killLatency:      prometheus.NewDesc(name("kill_latency_seconds"), "Time taken to send KILL signals.", nil, nil),
+
+		killerOpen:         prometheus.NewDesc(name("killer_pool_open_connections"), "Number of established connections in the killer pool.", nil, nil),
+		killerInUse:        prometheus.NewDesc(name("killer_pool_in_use_connections"), "Number of connections in use in the killer pool.", nil, nil),
+		killerIdle:         prometheus.NewDesc(name("killer_pool_idle_connections"), "Number of idle connections in the killer pool.", nil, nil),
+		killerWaitCount:    prometheus.NewDesc(name("killer_pool_wait_count_total"), "Number of connections waited for in the killer pool.", nil, nil),
+		killerWaitDuration: prometheus.NewDesc(name("killer_pool_wait_duration_seconds_total"), "Time spent waiting for connections in the killer pool.", nil, nil),
+	}
+}
+
+type collector struct {
+	db *sql.DB
+
+	queriesStarted   *prometheus.Desc
+	queriesFinished  *prometheus.Desc
+	contextsCanceled *prometheus.Desc
+	killsAttempted   *prometheus.Desc
+	kills            *prometheus.Desc
+	killsSkipped     *prometheus.Desc
+	killLatency      *prometheus.Desc
+
+	killerOpen         *prometheus.Desc
+	killerInUse        *prometheus.Desc
+	killerIdle         *prometheus.Desc
+	killerWaitCount    *prometheus.Desc
+	killerWaitDuration *prometheus.Desc
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queriesStarted
+	ch <- c.queriesFinished
+	ch <- c.contextsCanceled
+	ch <- c.killsAttempted
+	ch <- c.kills
+	ch <- c.killsSkipped
+	ch <- c.killLatency
+	ch <- c.killerOpen
+	ch <- c.killerInUse
+	ch <- c.killerIdle
+	ch <- c.killerWaitCount
+	ch <- c.killerWaitDuration
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.CancelStats()
+
+	ch <- prometheus.MustNewConstMetric(c.queriesStarted, prometheus.CounterValue, float64(stats.QueriesStarted))
+	ch <- prometheus.MustNewConstMetric(c.queriesFinished, prometheus.CounterValue, float64(stats.QueriesFinished))
+	ch <- prometheus.MustNewConstMetric(c.contextsCanceled, prometheus.CounterValue, float64(stats.ContextsCanceled))
+	ch <- prometheus.MustNewConstMetric(c.killsAttempted, prometheus.CounterValue, float64(stats.KillsAttempted))
+	ch <- prometheus.MustNewConstMetric(c.kills, prometheus.CounterValue, float64(stats.KillsSucceeded), "succeeded")
+	ch <- prometheus.MustNewConstMetric(c.kills, prometheus.CounterValue, float64(stats.KillsFailed), "failed")
+	ch <- prometheus.MustNewConstMetric(c.kills, prometheus.CounterValue, float64(stats.KillsTimedOut), "timed_out")
+	ch <- prometheus.MustNewConstMetric(c.killsSkipped, prometheus.CounterValue, float64(stats.KillsSkipped))
+
+	buckets := make(map[float64]uint64, len(stats.KillLatency.Buckets))
+	for i, b := range stats.KillLatency.Buckets {
+		buckets[b.Seconds()] = stats.KillLatency.Counts[i]
+	}
+	ch <- prometheus.MustNewConstHistogram(c.killLatency, stats.KillLatency.Count, stats.KillLatency.Sum.Seconds(), buckets)
+
+	if c.db.KillerPool == nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.killerOpen, prometheus.GaugeValue, float64(stats.KillerPool.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.killerInUse, prometheus.GaugeValue, float64(stats.KillerPool.InUse))
+	ch <- prometheus.MustNewConstMetric(c.killerIdle, prometheus.GaugeValue, float64(stats.KillerPool.Idle))
+	ch <- prometheus.MustNewConstMetric(c.killerWaitCount, prometheus.CounterValue, float64(stats.KillerPool.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.killerWaitDuration, prometheus.CounterValue, stats.KillerPool.WaitDuration.Seconds())
+}
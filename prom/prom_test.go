@@ -0,0 +1,72 @@
+package prom
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+func TestCollector(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^UPDATE`).WillDelayFor(10 * time.Second)
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.ExecContext(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = conn.ExecContext(ctx, "UPDATE users SET name = 'bob'")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	expected := `
+# HELP test_mysql_contexts_canceled_total Number of queries that finished with a canceled context.
+# TYPE test_mysql_contexts_canceled_total counter
+test_mysql_contexts_canceled_total 1
+# HELP test_mysql_kills_attempted_total Number of KILL signals attempted.
+# TYPE test_mysql_kills_attempted_total counter
+test_mysql_kills_attempted_total 1
+# HELP test_mysql_kills_skipped_total Number of KILL signals not sent because the query had already finished.
+# TYPE test_mysql_kills_skipped_total counter
+test_mysql_kills_skipped_total 0
+# HELP test_mysql_kills_total Number of KILL signals by outcome.
+# TYPE test_mysql_kills_total counter
+test_mysql_kills_total{outcome="failed"} 0
+test_mysql_kills_total{outcome="succeeded"} 1
+test_mysql_kills_total{outcome="timed_out"} 0
+# HELP test_mysql_queries_finished_total Number of queries finished.
+# TYPE test_mysql_queries_finished_total counter
+test_mysql_queries_finished_total 2
+# HELP test_mysql_queries_started_total Number of queries started.
+# TYPE test_mysql_queries_started_total counter
+test_mysql_queries_started_total 2
+`
+	c := NewCollector(pool, "test")
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"test_mysql_queries_started_total",
+		"test_mysql_queries_finished_total",
+		"test_mysql_contexts_canceled_total",
+		"test_mysql_kills_attempted_total",
+		"test_mysql_kills_total",
+		"test_mysql_kills_skipped_total",
+	))
+
+	// Every metric is valid, including the latency histogram and the killer pool
+	problems, err := testutil.CollectAndLint(c)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+	assert.Equal(t, 14, testutil.CollectAndCount(c))
+}
@@ -0,0 +1,86 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	stdSql "database/sql"
+)
+
+// QueryAll runs a query and returns every row scanned into a T.
+//
+// If T is a struct, columns are mapped to fields as described by Rows.ScanStruct.
+// Otherwise, the query must return a single column. As with Rows, a KILL signal
+// is sent if the context is canceled.
+//
+// Example:
+//
+//	type User struct {
+//	   ID   int64  `db:"id"`
+//	   Name string `db:"name"`
+//	}
+//
+//	users, err := sql.QueryAll[User](ctx, conn, "SELECT id, name FROM users WHERE active = ?", true)
+func QueryAll[T any](ctx context.Context, q SQLBasic, query string, args ...interface{}) ([]T, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []T
+	for rows.Next() {
+		var v T
+		if err := scanRow(rows, &v, cols); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// QueryOne runs a query and returns the first row scanned into a T.
+// The remaining rows are discarded. If the query selects no rows,
+// stdSql.ErrNoRows is returned. See QueryAll.
+func QueryOne[T any](ctx context.Context, q SQLBasic, query string, args ...interface{}) (T, error) {
+	var v T
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return v, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return v, err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return v, err
+		}
+		return v, stdSql.ErrNoRows
+	}
+	if err := scanRow(rows, &v, cols); err != nil {
+		return v, err
+	}
+	return v, rows.Close()
+}
+
+// scanRow scans the current row into dest.
+func scanRow(rows *Rows, dest interface{}, cols []string) error {
+	targets, err := scanTargets(dest, cols)
+	if err != nil {
+		return err
+	}
+	return rows.Scan(targets...)
+}
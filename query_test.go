@@ -0,0 +1,74 @@
+package sql_test
+
+import (
+	"context"
+	stdSql "database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+type user struct {
+	ID       int64 `db:"id"`
+	Name     string
+	Email    stdSql.NullString `db:"email"`
+	password string
+	Ignored  string `db:"-"`
+}
+
+func TestQueryAll(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT id, name, email`).WillReturnRows([]string{"id", "name", "email"},
+		[]interface{}{1, "alice", "alice@example.com"},
+		[]interface{}{2, "bob", nil},
+	)
+	rec.Expect(`^SELECT name`).WillReturnRows([]string{"name"}, []interface{}{"alice"}, []interface{}{"bob"})
+	rec.Expect(`^SELECT password`).WillReturnRows([]string{"password"}, []interface{}{"secret"})
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	users, err := sql.QueryAll[user](ctx, conn, "SELECT id, name, email FROM users")
+	require.NoError(t, err)
+	assert.Equal(t, []user{
+		{ID: 1, Name: "alice", Email: stdSql.NullString{String: "alice@example.com", Valid: true}},
+		{ID: 2, Name: "bob"},
+	}, users)
+
+	names, err := sql.QueryAll[string](ctx, conn, "SELECT name FROM users")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, names)
+
+	_, err = sql.QueryAll[user](ctx, conn, "SELECT password FROM users")
+	assert.EqualError(t, err, `sql: no field for column "password" in sql_test.user`)
+}
+
+func TestQueryOne(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT COUNT`).WillReturnRows([]string{"COUNT(*)"}, []interface{}{42})
+	rec.Expect(`^SELECT name`).WillReturnRows([]string{"name"})
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	n, err := sql.QueryOne[int](ctx, conn, "SELECT COUNT(*) FROM users")
+	require.NoError(t, err)
+	assert.Equal(t, 42, n)
+
+	_, err = sql.QueryOne[string](ctx, conn, "SELECT name FROM users WHERE id = ?", 3)
+	assert.Equal(t, stdSql.ErrNoRows, err)
+}
@@ -0,0 +1,240 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Redaction sets how query arguments are redacted before they are logged.
+// The literals in logged queries are also replaced using SanitizeQuery.
+//
+// Example:
+//
+//	pool.Redact = &sql.Redaction{
+//	   Columns:  []string{"password", "email"},
+//	   Patterns: []*regexp.Regexp{regexp.MustCompile(`^\d{13,19}$`)}, // Card numbers
+//	}
+type Redaction struct {
+
+	// Columns are the names of columns (case-insensitive) whose arguments are redacted.
+	// The column an argument belongs to is determined from the query. For example:
+	// "password = ?", "password IN (?, ?)" and "INSERT INTO t (password) VALUES (?)".
+	Columns []string
+
+	// Patterns redact string and []byte arguments that match any of the regular expressions.
+	Patterns []*regexp.Regexp
+
+	// Mask replaces redacted arguments.
+	// An empty value defaults to "[REDACTED]".
+	Mask string
+}
+
+// Args returns a copy of args with the redacted arguments replaced by Mask.
+// If r is nil, args is returned unchanged.
+func (r *Redaction) Args(query string, args []interface{}) []interface{} {
+	if r == nil || len(args) == 0 {
+		return args
+	}
+
+	mask := r.Mask
+	if mask == "" {
+		mask = "[REDACTED]"
+	}
+
+	var columns []string
+	if len(r.Columns) > 0 {
+		columns = placeholderColumns(query)
+	}
+
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		if (i < len(columns) && r.redactColumn(columns[i])) || r.redactValue(arg) {
+			out[i] = mask
+		} else {
+			out[i] = arg
+		}
+	}
+	return out
+}
+
+// Query returns query with its literals replaced using SanitizeQuery.
+// If r is nil, query is returned unchanged.
+func (r *Redaction) Query(query string) string {
+	if r == nil {
+		return query
+	}
+	return SanitizeQuery(query)
+}
+
+func (r *Redaction) redactColumn(column string) bool {
+	if column == "" {
+		return false
+	}
+	for _, c := range r.Columns {
+		if strings.EqualFold(c, column) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redaction) redactValue(arg interface{}) bool {
+	var s string
+	switch v := arg.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return false
+	}
+
+	for _, p := range r.Patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlToken is a token of a query. Literals, comments and whitespace are discarded.
+type sqlToken struct {
+	text   string
+	ident  bool // Identifier or keyword
+	quoted bool // Identifier quoted using backticks
+}
+
+// tokenize splits query into tokens.
+func tokenize(query string) []sqlToken {
+	var toks []sqlToken
+
+	for _, l := range scanQuery(query) {
+		switch l.kind {
+		case lexSpace, lexComment:
+		case lexString:
+			toks = append(toks, sqlToken{text: "'"})
+		case lexQuoted:
+			name := strings.TrimSuffix(l.text[1:], "`")
+			toks = append(toks, sqlToken{text: strings.Replace(name, "``", "`", -1), ident: true, quoted: true})
+		case lexWord:
+			toks = append(toks, sqlToken{text: l.text, ident: true})
+		default:
+			toks = append(toks, sqlToken{text: l.text})
+		}
+	}
+	return toks
+}
+
+// isKeyword reports whether t is the unquoted keyword kw.
+func (t sqlToken) isKeyword(kw string) bool {
+	return t.ident && !t.quoted && strings.EqualFold(t.text, kw)
+}
+
+// placeholderColumns returns the column that each ? placeholder in query is
+// compared with or inserted into. An empty string is returned for placeholders
+// whose column can not be determined.
+func placeholderColumns(query string) []string {
+	toks := tokenize(query)
+
+	var (
+		cols []string
+
+		insertCols []string // Column list of an INSERT ... VALUES
+		inValues   bool
+		depth      int
+		pos        int // Position within the current VALUES tuple
+	)
+
+	for i, t := range toks {
+		switch {
+		case t.text == "?":
+			col := ""
+			if inValues && depth == 1 {
+				if pos < len(insertCols) {
+					col = insertCols[pos]
+				}
+			} else {
+				col = comparedColumn(toks[:i])
+			}
+			cols = append(cols, col)
+		case t.isKeyword("VALUES") || t.isKeyword("VALUE"):
+			insertCols = columnList(toks[:i])
+			inValues, depth = insertCols != nil, 0
+		case !inValues:
+		case t.text == "(":
+			if depth == 0 {
+				pos = 0
+			}
+			depth++
+		case t.text == ")":
+			depth--
+		case t.text == ",":
+			if depth == 1 {
+				pos++
+			}
+		case depth == 0 && t.ident:
+			// For example: ON DUPLICATE KEY UPDATE
+			inValues = false
+		}
+	}
+	return cols
+}
+
+// comparedColumn returns the column compared with the placeholder that follows toks.
+// For example: "col = ?", "t.col LIKE ?" and "col NOT IN (?, ?)".
+func comparedColumn(toks []sqlToken) string {
+	j := len(toks) - 1
+
+	// Skip the earlier elements of an IN list
+	for j >= 0 && (toks[j].text == "(" || toks[j].text == "," || toks[j].text == "?") {
+		j--
+	}
+	if j < 1 {
+		return ""
+	}
+
+	op := toks[j]
+	if !(op.text != "" && strings.IndexByte("<>=!", op.text[0]) >= 0) &&
+		!op.isKeyword("LIKE") && !op.isKeyword("IN") && !op.isKeyword("REGEXP") && !op.isKeyword("RLIKE") {
+		return ""
+	}
+
+	j--
+	if toks[j].isKeyword("NOT") {
+		j--
+	}
+	if j < 0 || !toks[j].ident {
+		return ""
+	}
+	return toks[j].text
+}
+
+// columnList returns the column list that ends toks. For example: "(a, b, c)".
+func columnList(toks []sqlToken) []string {
+	j := len(toks) - 1
+	if j < 0 || toks[j].text != ")" {
+		return nil
+	}
+
+	var cols []string
+	for j--; j >= 0; j-- {
+		switch {
+		case toks[j].text == "(":
+			// Reverse
+			for l, r := 0, len(cols)-1; l < r; l, r = l+1, r-1 {
+				cols[l], cols[r] = cols[r], cols[l]
+			}
+			return cols
+		case toks[j].ident:
+			cols = append(cols, toks[j].text)
+			if j > 0 && toks[j-1].text == "." {
+				j -= 2 // Skip the table qualifier
+			}
+		case toks[j].text != ",":
+			return nil
+		}
+	}
+	return nil
+}
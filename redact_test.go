@@ -0,0 +1,53 @@
+package sql
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaceholderColumns(t *testing.T) {
+	tests := []struct {
+		query string
+		cols  []string
+	}{
+		{"SELECT * FROM users WHERE email = ? AND `password`=?", []string{"email", "password"}},
+		{"SELECT * FROM users u WHERE u.id IN (?, ?) AND name NOT LIKE ?", []string{"id", "id", "name"}},
+		{"SELECT * FROM t WHERE a >= ? AND b <> ? AND c = LOWER(?)", []string{"a", "b", ""}},
+		{"SELECT * FROM t WHERE note = '?' AND /* x = ? */ a = ?", []string{"a"}},
+		{"UPDATE users SET password = ?, updated = NOW() WHERE id = ?", []string{"password", "id"}},
+		{"INSERT INTO users (id, `email`, password) VALUES (?, ?, ?), (?, ?, ?)", []string{"id", "email", "password", "id", "email", "password"}},
+		{"INSERT INTO users (id, password) VALUES (?, ?) ON DUPLICATE KEY UPDATE password = ?", []string{"id", "password", "password"}},
+		{"INSERT INTO t VALUES (?, ?)", []string{"", ""}},
+		{"SELECT * FROM t WHERE `a``b` = ? -- c = ?\n AND 5 = ?", []string{"a`b", ""}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.cols, placeholderColumns(tt.query), tt.query)
+	}
+}
+
+func TestRedactionArgs(t *testing.T) {
+	var r *Redaction
+	args := []interface{}{"a", 1}
+	assert.Equal(t, args, r.Args("SELECT ?, ?", args))
+
+	r = &Redaction{
+		Columns:  []string{"PASSWORD"},
+		Patterns: []*regexp.Regexp{regexp.MustCompile(`^\d{16}$`)},
+	}
+
+	query := "UPDATE users SET password = ?, card = ?, age = ? WHERE id = ?"
+	redacted := r.Args(query, []interface{}{"hunter2", []byte("4111111111111111"), 42, "4111111111111111"})
+	assert.Equal(t, []interface{}{"[REDACTED]", "[REDACTED]", 42, "[REDACTED]"}, redacted)
+}
+
+func TestRedactionQuery(t *testing.T) {
+	var r *Redaction
+	query := "SELECT * FROM users WHERE email = 'alice@example.com' AND id = ?"
+	assert.Equal(t, query, r.Query(query))
+
+	r = &Redaction{}
+	assert.Equal(t, "SELECT * FROM users WHERE email = ? AND id = ?", r.Query(query))
+}
@@ -0,0 +1,249 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	stdSql "database/sql"
+	"errors"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	errNotReplica         = errors.New("sql: server is not a replica")
+	errReplicationStopped = errors.New("sql: replication is not running")
+)
+
+// ReplicaSet is a group of read replicas. It periodically measures the
+// replication lag of each replica and routes reads away from replicas
+// that have fallen too far behind the primary.
+//
+// Start must be called to begin monitoring. Until the first measurement
+// completes, all replicas are considered stale and reads are served by
+// the primary.
+type ReplicaSet struct {
+
+	// Replicas are the replica databases. Each replica should be
+	// configured with its own KillerPool (and KillTimeout) because a KILL
+	// signal must be sent to the server that holds the connection.
+	// Every other setting (Interceptors, Tracer, Logger, Tags, TxMonitor etc.)
+	// of a Conn obtained from a replica is taken from the primary DB.
+	Replicas []*DB
+
+	// MaxLag is the maximum replication lag tolerated. Replicas that lag
+	// more than MaxLag are excluded until they catch up.
+	MaxLag time.Duration
+
+	// Interval sets how often the replication lag is measured.
+	// A value of zero defaults to 1 second.
+	Interval time.Duration
+
+	// HeartbeatQuery is an optional query that returns the replication lag
+	// in seconds as a single value. It is useful when a heartbeat table
+	// (such as pt-heartbeat) is maintained on the primary.
+	// If not provided, Seconds_Behind_Source from SHOW REPLICA STATUS is used.
+	HeartbeatQuery string
+
+	// MaxGTIDWait is the maximum time ReadConnAfter waits for a replica to
+	// apply the required transactions when the context has no deadline.
+	// A value of zero defaults to 1 second.
+	MaxGTIDWait time.Duration
+
+	lock    sync.RWMutex
+	healthy []*DB
+	stop    chan struct{}
+	next    uint32
+}
+
+// Start begins measuring the replication lag of each replica in the background.
+// Calling Start on a ReplicaSet that has already started does nothing.
+func (rs *ReplicaSet) Start() {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	if rs.stop != nil {
+		return
+	}
+	rs.stop = make(chan struct{})
+
+	interval := rs.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			ctx, cancelFunc := context.WithTimeout(context.Background(), interval)
+			rs.Check(ctx)
+			cancelFunc()
+
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}(rs.stop)
+}
+
+// Stop ends the background measurement of replication lag.
+func (rs *ReplicaSet) Stop() {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	if rs.stop != nil {
+		close(rs.stop)
+		rs.stop = nil
+	}
+}
+
+// Check measures the replication lag of each replica once and
+// updates which replicas are eligible to serve reads.
+// It is called periodically after Start.
+func (rs *ReplicaSet) Check(ctx context.Context) {
+	var (
+		wg      sync.WaitGroup
+		healthy = make([]bool, len(rs.Replicas))
+	)
+
+	for i := range rs.Replicas {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lag, err := rs.lag(ctx, rs.Replicas[i])
+			healthy[i] = err == nil && lag <= rs.MaxLag
+		}(i)
+	}
+	wg.Wait()
+
+	var dbs []*DB
+	for i := range rs.Replicas {
+		if healthy[i] {
+			dbs = append(dbs, rs.Replicas[i])
+		}
+	}
+
+	rs.lock.Lock()
+	rs.healthy = dbs
+	rs.lock.Unlock()
+}
+
+// maxGTIDWait returns how long a replica can wait for transactions to be applied.
+func (rs *ReplicaSet) maxGTIDWait() time.Duration {
+	if rs.MaxGTIDWait <= 0 {
+		return time.Second
+	}
+	return rs.MaxGTIDWait
+}
+
+// pick returns a replica that is not lagging behind, in round-robin order.
+// It returns nil if every replica is stale.
+func (rs *ReplicaSet) pick() *DB {
+	rs.lock.RLock()
+	defer rs.lock.RUnlock()
+
+	if len(rs.healthy) == 0 {
+		return nil
+	}
+	n := atomic.AddUint32(&rs.next, 1)
+	return rs.healthy[int(n)%len(rs.healthy)]
+}
+
+// replicaConn returns a single connection to replica that is otherwise
+// configured by db.
+func (db *DB) replicaConn(ctx context.Context, replica *DB) (*Conn, error) {
+	conn, err := replica.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The replica's KillerPool and KillTimeout are kept
+	replica.untrack(conn.tracked)
+	runtime.SetFinalizer(conn, nil)
+	conn.db = db
+	if db.Logger != nil {
+		runtime.SetFinalizer(conn, (*Conn).leaked)
+	}
+	db.trackConn(conn)
+	return conn, nil
+}
+
+// lag measures the replication lag of a replica.
+func (rs *ReplicaSet) lag(ctx context.Context, replica *DB) (time.Duration, error) {
+
+	if rs.HeartbeatQuery != "" {
+		var secs stdSql.NullFloat64
+		err := replica.DB.QueryRowContext(ctx, rs.HeartbeatQuery).Scan(&secs)
+		if err != nil {
+			return 0, err
+		}
+		if !secs.Valid {
+			return 0, errReplicationStopped
+		}
+		return time.Duration(secs.Float64 * float64(time.Second)), nil
+	}
+
+	// SHOW REPLICA STATUS is only available from MySQL 8.0.22.
+	lag, err := secondsBehind(ctx, replica.DB, "SHOW REPLICA STATUS", "Seconds_Behind_Source")
+	if err != nil && err != errNotReplica && err != errReplicationStopped && ctx.Err() == nil {
+		lag, err = secondsBehind(ctx, replica.DB, "SHOW SLAVE STATUS", "Seconds_Behind_Master")
+	}
+	return lag, err
+}
+
+// secondsBehind reads the replication lag from the column of a
+// SHOW REPLICA STATUS style query.
+func secondsBehind(ctx context.Context, db StdSQLDB, query string, column string) (time.Duration, error) {
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, errNotReplica
+	}
+
+	vals := make([]stdSql.RawBytes, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+
+	err = rows.Scan(dest...)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, col := range cols {
+		if col != column {
+			continue
+		}
+		if vals[i] == nil {
+			return 0, errReplicationStopped
+		}
+		secs, err := strconv.ParseInt(string(vals[i]), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(secs) * time.Second, nil
+	}
+
+	return 0, errors.New("sql: " + column + " not found")
+}
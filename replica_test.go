@@ -0,0 +1,179 @@
+package sql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+// readFrom returns the index of the recorder that served a query run
+// on a connection obtained using ReadConn, or -1 if none did.
+func readFrom(t *testing.T, pool *sql.DB, recs ...*sqltest.Recorder) int {
+	t.Helper()
+
+	before := make([]int, len(recs))
+	for i, rec := range recs {
+		before[i] = len(rec.Statements())
+	}
+
+	conn, err := pool.ReadConn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.ExecContext(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+
+	for i, rec := range recs {
+		if len(rec.Statements()) > before[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestReadConnHeartbeat(t *testing.T) {
+	primary := sqltest.NewRecorder()
+
+	current := sqltest.NewRecorder()
+	current.Expect(`^SELECT lag`).WillReturnRows([]string{"lag"}, []interface{}{0.5})
+
+	lagging := sqltest.NewRecorder()
+	lagging.Expect(`^SELECT lag`).WillReturnRows([]string{"lag"}, []interface{}{5.0})
+
+	stopped := sqltest.NewRecorder()
+	stopped.Expect(`^SELECT lag`).WillReturnRows([]string{"lag"}, []interface{}{nil})
+
+	failing := sqltest.NewRecorder()
+	failing.Expect(`^SELECT lag`).WillReturnError(errors.New("table does not exist"))
+
+	pool := primary.DB()
+	pool.Replicas = &sql.ReplicaSet{
+		Replicas:       []*sql.DB{current.DB(), lagging.DB(), stopped.DB(), failing.DB()},
+		MaxLag:         time.Second,
+		HeartbeatQuery: "SELECT lag FROM heartbeat",
+	}
+	defer pool.Close()
+
+	recs := []*sqltest.Recorder{primary, current, lagging, stopped, failing}
+
+	// Every replica is considered stale until the lag is measured
+	assert.Equal(t, 0, readFrom(t, pool, recs...))
+
+	pool.Replicas.Check(context.Background())
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, 1, readFrom(t, pool, recs...))
+	}
+}
+
+func TestReadConnReplicaStatus(t *testing.T) {
+	primary := sqltest.NewRecorder()
+
+	source := sqltest.NewRecorder()
+	source.Expect(`^SHOW REPLICA STATUS`).WillReturnRows([]string{"Replica_IO_State", "Seconds_Behind_Source"}, []interface{}{"Waiting", int64(0)})
+
+	// Servers older than MySQL 8.0.22 only support SHOW SLAVE STATUS
+	master := sqltest.NewRecorder()
+	master.Expect(`^SHOW REPLICA STATUS`).WillReturnError(errors.New("syntax error"))
+	master.Expect(`^SHOW SLAVE STATUS`).WillReturnRows([]string{"Slave_IO_State", "Seconds_Behind_Master"}, []interface{}{"Waiting", int64(1)})
+
+	lagging := sqltest.NewRecorder()
+	lagging.Expect(`^SHOW REPLICA STATUS`).WillReturnRows([]string{"Seconds_Behind_Source"}, []interface{}{int64(60)})
+
+	stopped := sqltest.NewRecorder()
+	stopped.Expect(`^SHOW REPLICA STATUS`).WillReturnRows([]string{"Seconds_Behind_Source"}, []interface{}{nil})
+
+	// Not a replica, so no rows are returned
+	notReplica := sqltest.NewRecorder()
+	notReplica.Expect(`^SHOW REPLICA STATUS`).WillReturnRows([]string{"Seconds_Behind_Source"})
+
+	pool := primary.DB()
+	pool.Replicas = &sql.ReplicaSet{
+		Replicas: []*sql.DB{source.DB(), master.DB(), lagging.DB(), stopped.DB(), notReplica.DB()},
+		MaxLag:   time.Second,
+	}
+	defer pool.Close()
+
+	pool.Replicas.Check(context.Background())
+
+	// Reads are spread across the replicas within MaxLag in round-robin order
+	recs := []*sqltest.Recorder{primary, source, master, lagging, stopped, notReplica}
+	counts := map[int]int{}
+	for i := 0; i < 4; i++ {
+		counts[readFrom(t, pool, recs...)]++
+	}
+	assert.Equal(t, map[int]int{1: 2, 2: 2}, counts)
+
+	var queries []string
+	for _, s := range notReplica.Statements() {
+		queries = append(queries, s.Query)
+	}
+	assert.NotContains(t, queries, "SHOW SLAVE STATUS")
+}
+
+func TestReadConnAllStale(t *testing.T) {
+	primary := sqltest.NewRecorder()
+
+	lagging := sqltest.NewRecorder()
+	lagging.Expect(`^SELECT lag`).WillReturnRows([]string{"lag"}, []interface{}{5.0})
+
+	pool := primary.DB()
+	pool.Replicas = &sql.ReplicaSet{
+		Replicas:       []*sql.DB{lagging.DB()},
+		MaxLag:         time.Second,
+		HeartbeatQuery: "SELECT lag FROM heartbeat",
+	}
+	defer pool.Close()
+
+	pool.Replicas.Check(context.Background())
+	assert.Equal(t, 0, readFrom(t, pool, primary, lagging))
+}
+
+func TestReadConnConfiguredByPrimary(t *testing.T) {
+	primary := sqltest.NewRecorder()
+
+	replica := sqltest.NewRecorder()
+	replica.Expect(`^SELECT lag`).WillReturnRows([]string{"lag"}, []interface{}{0.0})
+	replica.Expect(`SLEEP`).WillDelayFor(10 * time.Second)
+
+	var intercepted []string
+
+	pool := primary.DB()
+	pool.Tags = map[string]string{"app": "api"}
+	pool.Interceptors = []sql.Interceptor{func(ctx context.Context, call *sql.Call, next sql.Handler) error {
+		intercepted = append(intercepted, call.Query)
+		return next(ctx, call)
+	}}
+	pool.Replicas = &sql.ReplicaSet{
+		Replicas:       []*sql.DB{replica.DB()},
+		MaxLag:         time.Second,
+		HeartbeatQuery: "SELECT lag FROM heartbeat",
+	}
+	defer pool.Close()
+	pool.Replicas.Check(context.Background())
+
+	assert.Equal(t, 1, readFrom(t, pool, primary, replica))
+	assert.Equal(t, []string{"/* app='api' */ SELECT 1"}, intercepted)
+	stmts := replica.Statements()
+	assert.Equal(t, "/* app='api' */ SELECT 1", stmts[len(stmts)-1].Query)
+
+	// The KILL signal is sent to the replica but recorded by the primary DB
+	conn, err := pool.ReadConn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = conn.ExecContext(ctx, "SELECT SLEEP(10)")
+	assert.Error(t, err)
+
+	assert.Eventually(t, func() bool { return len(replica.Kills()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Empty(t, primary.Kills())
+	assert.Equal(t, uint64(1), pool.CancelStats().KillsAttempted)
+}
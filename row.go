@@ -3,16 +3,29 @@ package sql
 import (
 	"context"
 	stdSql "database/sql"
-	"time"
+	"errors"
+	"sync/atomic"
 )
 
 // Row is the result of calling QueryRow to select a single row.
 type Row struct {
-	ctx          context.Context
-	row          *stdSql.Row
-	killerPool   StdSQLDB
-	connectionID string
-	kto          time.Duration
+	session
+	ctx  context.Context
+	rows *stdSql.Rows
+	err  error // Returned by the query or an Interceptor
+
+	killed int32
+	call   *Call
+}
+
+// kill sends a KILL QUERY signal. It is only sent once.
+func (r *Row) kill() {
+	if atomic.CompareAndSwapInt32(&r.killed, 0, 1) {
+		if r.call != nil {
+			r.call.Killed = true
+		}
+		r.db.kill(r.ctx, r.killerPool, r.connectionID, r.kto, r.comment)
+	}
 }
 
 // Scan copies the columns from the matched row into the values
@@ -21,9 +34,61 @@ type Row struct {
 // Scan uses the first row and discards the rest. If no row matches
 // the query, Scan returns ErrNoRows.
 func (r *Row) Scan(dest ...interface{}) error {
-	err := r.row.Scan(dest...)
-	if r.ctx.Err() != nil {
-		kill(r.killerPool, r.connectionID, r.kto)
+	return r.scan(func([]string) ([]interface{}, error) {
+		for _, dp := range dest {
+			if _, ok := dp.(*stdSql.RawBytes); ok {
+				return nil, errors.New("sql: RawBytes isn't allowed on Row.Scan")
+			}
+		}
+		return dest, nil
+	})
+}
+
+// ScanStruct copies the columns from the matched row into the fields of the
+// struct pointed at by dest. See the documentation on Rows.ScanStruct for details.
+// If no row matches the query, ScanStruct returns ErrNoRows.
+func (r *Row) ScanStruct(dest interface{}) error {
+	return r.scan(func(cols []string) ([]interface{}, error) {
+		return structTargets(dest, cols)
+	})
+}
+
+// scan mirrors the Scan method of *stdSql.Row. targets returns the values
+// that the columns are scanned into.
+func (r *Row) scan(targets func(cols []string) ([]interface{}, error)) error {
+	if r.err != nil {
+		return r.err
 	}
-	return err
+
+	defer func() {
+		if r.ctx.Err() != nil {
+			r.kill()
+		}
+		if r.call != nil {
+			r.call.close()
+		}
+	}()
+	defer r.rows.Close()
+
+	cols, err := r.rows.Columns()
+	if err != nil {
+		return err
+	}
+	dest, err := targets(cols)
+	if err != nil {
+		return err
+	}
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return stdSql.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+
+	// Make sure the query can be processed to completion with no errors
+	return r.rows.Close()
 }
@@ -3,17 +3,30 @@ package sql
 import (
 	"context"
 	stdSql "database/sql"
-	"time"
+	"sync"
+	"sync/atomic"
 )
 
 // Rows is the result of a query. Its cursor starts before the first row
 // of the result set. Use Next to advance from row to row.
 type Rows struct {
-	ctx          context.Context
-	rows         *stdSql.Rows
-	killerPool   StdSQLDB
-	connectionID string
-	kto          time.Duration
+	session
+	ctx  context.Context
+	rows *stdSql.Rows
+
+	query   string
+	closed  int32
+	killed  int32
+	tracked *trackedHandle
+
+	// Set when Next has advanced to the next result set
+	advanced bool
+
+	// The column types of the current result set, cached by ScanSlice
+	columnTypes []*stdSql.ColumnType
+
+	call      *Call
+	closeOnce sync.Once
 }
 
 // Unleak will release the reference to the killerPool
@@ -22,6 +35,7 @@ func (rs *Rows) Unleak() {
 	rs.killerPool = nil
 	rs.connectionID = ""
 	rs.kto = 0
+	rs.tracked.markUnleaked()
 }
 
 // Close closes the Rows, preventing further enumeration. If Next is called
@@ -29,20 +43,65 @@ func (rs *Rows) Unleak() {
 // the Rows are closed automatically and it will suffice to check the
 // result of Err. Close is idempotent and does not affect the result of Err.
 func (rs *Rows) Close() error {
+	atomic.StoreInt32(&rs.closed, 1)
+	rs.tracked.markClosed()
 	err := rs.rows.Close()
 	if rs.ctx.Err() != nil {
-		kill(rs.killerPool, rs.connectionID, rs.kto)
+		rs.kill()
 	}
+	rs.finish()
 	rs.Unleak()
 	return err
 }
 
+// kill sends a KILL QUERY signal. It is only sent once, no matter how many
+// methods observe the canceled context.
+//
+// The operation is only reported as killed (in Call.Killed, CancelStats and
+// the operation's span) if the context was canceled. Otherwise, the KILL was
+// requested using Kill.
+func (rs *Rows) kill() error {
+	if !atomic.CompareAndSwapInt32(&rs.killed, 0, 1) {
+		return nil
+	}
+	if rs.ctx.Err() == nil {
+		return kill(rs.killerPool, rs.connectionID, rs.kto)
+	}
+	if rs.call != nil {
+		rs.call.Killed = true
+	}
+	return rs.db.kill(rs.ctx, rs.killerPool, rs.connectionID, rs.kto, rs.comment)
+}
+
+// finish calls the functions registered using Call.OnClose once the Rows are closed.
+func (rs *Rows) finish() {
+	rs.closeOnce.Do(func() {
+		if rs.call != nil {
+			rs.call.close()
+		}
+	})
+}
+
+// Kill sends a KILL QUERY signal if the Rows are still open. It should be called
+// when iteration is stopped before the result set has been read (for example,
+// because writing the rows failed) so that Close does not have to read (and
+// discard) the remaining rows. Close must still be called.
+//
+// Unless the context has been canceled, the operation is not reported as
+// killed (see Call.Killed).
+func (rs *Rows) Kill() error {
+	if atomic.LoadInt32(&rs.closed) == 0 {
+		return rs.kill()
+	}
+	return nil
+}
+
 // ColumnTypes returns column information such as column type, length,
 // and nullable. Some information may not be available from some drivers.
 func (rs *Rows) ColumnTypes() ([]*stdSql.ColumnType, error) {
 	ct, err := rs.rows.ColumnTypes()
 	if rs.ctx.Err() != nil {
-		kill(rs.killerPool, rs.connectionID, rs.kto)
+		rs.kill()
 	}
 	return ct, err
 }
@@ -52,7 +111,7 @@ func (rs *Rows) ColumnTypes() ([]*stdSql.ColumnType, error) {
 func (rs *Rows) Columns() ([]string, error) {
 	cols, err := rs.rows.Columns()
 	if rs.ctx.Err() != nil {
-		kill(rs.killerPool, rs.connectionID, rs.kto)
+		rs.kill()
 	}
 	return cols, err
 }
@@ -62,7 +121,7 @@ func (rs *Rows) Columns() ([]string, error) {
 func (rs *Rows) Err() error {
 	err := rs.rows.Err()
 	if rs.ctx.Err() != nil {
-		kill(rs.killerPool, rs.connectionID, rs.kto)
+		rs.kill()
 	}
 	return err
 }
@@ -74,7 +133,34 @@ func (rs *Rows) Err() error {
 //
 // Every call to Scan, even the first one, must be preceded by a call to Next.
 func (rs *Rows) Next() bool {
-	return rs.rows.Next()
+	if rs.advanced {
+		return false
+	}
+	if rs.rows.Next() {
+		return true
+	}
+
+	// database/sql closes the rows unless there is a further result set.
+	// Advancing to it determines which (NextResultSet then reports it).
+	if rs.rows.NextResultSet() {
+		rs.advanced = true
+		rs.columnTypes = nil
+	} else {
+		rs.autoClose()
+	}
+	return false
+}
+
+// autoClose records that the rows were closed by database/sql because there are
+// no further result sets (or an error occurred).
+func (rs *Rows) autoClose() {
+	atomic.StoreInt32(&rs.closed, 1)
+	rs.tracked.markClosed()
+	if rs.ctx.Err() != nil {
+		rs.kill()
+	}
+	rs.finish()
+	rs.Unleak()
 }
 
 // NextResultSet prepares the next result set for reading. It reports whether
@@ -86,7 +172,19 @@ func (rs *Rows) Next() bool {
 // scanning. If there are further result sets they may not have rows in the result
 // set.
 func (rs *Rows) NextResultSet() bool {
-	return rs.rows.NextResultSet()
+	if rs.advanced {
+		rs.advanced = false
+		return true
+	}
+	if atomic.LoadInt32(&rs.closed) == 1 {
+		return false
+	}
+	if !rs.rows.NextResultSet() {
+		rs.autoClose()
+		return false
+	}
+	rs.columnTypes = nil
+	return true
 }
 
 // Scan copies the columns in the current row into the values pointed
@@ -149,7 +247,27 @@ func (rs *Rows) NextResultSet() bool {
 func (rs *Rows) Scan(dest ...interface{}) error {
 	err := rs.rows.Scan(dest...)
 	if rs.ctx.Err() != nil {
-		kill(rs.killerPool, rs.connectionID, rs.kto)
+		rs.kill()
 	}
 	return err
 }
+
+// ScanStruct copies the columns in the current row into the fields of the struct
+// pointed at by dest. Each column is mapped to the field with a matching `db` tag
+// or, if it has no tag, whose name converted to snake_case matches (case-insensitively).
+// For example: UserID is mapped to the user_id column. A tag of "-" excludes the field.
+//
+// The fields of embedded structs are mapped as if they belonged to the outer struct.
+// NULL values can be scanned into pointer fields and types such as sql.NullString.
+// An error is returned if a column does not map to a field. Mappings are cached per type.
+func (rs *Rows) ScanStruct(dest interface{}) error {
+	cols, err := rs.rows.Columns()
+	if err != nil {
+		return err
+	}
+	targets, err := structTargets(dest, cols)
+	if err != nil {
+		return err
+	}
+	return rs.Scan(targets...)
+}
@@ -0,0 +1,110 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	stdSql "database/sql"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+const (
+	// maxTxAttempts is the maximum number of times RunInTx attempts a transaction.
+	maxTxAttempts = 10
+
+	txRetryMinDelay = 10 * time.Millisecond
+	txRetryMaxDelay = time.Second
+)
+
+// RunInTx runs fn inside a transaction on a single connection.
+// See Conn.RunInTx for details.
+func (db *DB) RunInTx(ctx context.Context, opts *TxOptions, fn func(tx *Tx) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.RunInTx(ctx, opts, fn)
+}
+
+// RunInTx runs fn inside a transaction started using BeginTxWithOptions.
+//
+// The transaction is committed if fn returns nil. It is rolled back if fn
+// returns an error or panics (the panic is propagated after the rollback).
+//
+// If the transaction fails due to a deadlock (error 1213) or a lock wait timeout
+// (error 1205), the whole transaction (including fn) is retried using exponential
+// backoff. It is attempted at most 10 times, and is not retried once the context
+// is canceled. fn must therefore be safe to call multiple times.
+//
+// If the context is canceled while fn is running, the running query is killed
+// and the transaction is rolled back.
+func (c *Conn) RunInTx(ctx context.Context, opts *TxOptions, fn func(tx *Tx) error) error {
+
+	delay := txRetryMinDelay
+
+	for attempt := 1; ; attempt++ {
+		err := c.runInTx(ctx, opts, fn)
+		if err == nil || !isRetryableTxErr(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		// Wait before retrying (with jitter to prevent repeated deadlocks)
+		timer := time.NewTimer(delay/2 + time.Duration(rand.Int63n(int64(delay))))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if delay > txRetryMaxDelay {
+			delay = txRetryMaxDelay
+		}
+	}
+}
+
+// runInTx makes a single attempt at running fn inside a transaction.
+func (c *Conn) runInTx(ctx context.Context, opts *TxOptions, fn func(tx *Tx) error) error {
+
+	tx, err := c.BeginTxWithOptions(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			c.rollback(ctx, tx)
+			panic(p)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		c.rollback(ctx, tx)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// rollback rolls back tx and logs the error if it fails.
+func (c *Conn) rollback(ctx context.Context, tx *Tx) {
+	err := tx.Rollback()
+
+	// If the context was canceled or MaxDuration was exceeded, the transaction
+	// has already been rolled back.
+	if err != nil && !errors.Is(err, stdSql.ErrTxDone) && err != ErrTxTimeout {
+		c.db.log(ctx, LogError, "rollback failed", LogAttr{logKeyConnectionID, c.connectionID}, LogAttr{logKeyError, err})
+	}
+}
+
+// isRetryableTxErr reports whether a transaction that failed with err can be retried.
+func isRetryableTxErr(err error) bool {
+	num, ok := mysqlErrNumber(err)
+	return ok && (num == erLockDeadlock || num == erLockWaitTimeout)
+}
@@ -0,0 +1,120 @@
+package sql_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+// transactions returns the BEGIN, COMMIT and ROLLBACK statements recorded by rec.
+func transactions(rec *sqltest.Recorder) []string {
+	var queries []string
+	for _, s := range rec.Statements() {
+		switch s.Query {
+		case "BEGIN", "COMMIT", "ROLLBACK":
+			queries = append(queries, s.Query)
+		}
+	}
+	return queries
+}
+
+func TestRunInTx(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	pool := rec.DB()
+	defer pool.Close()
+
+	err := pool.RunInTx(context.Background(), nil, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(context.Background(), "UPDATE users SET name = 'alice'")
+		return err
+	})
+	require.NoError(t, err)
+
+	errFailed := errors.New("failed")
+	err = pool.RunInTx(context.Background(), nil, func(tx *sql.Tx) error { return errFailed })
+	assert.Equal(t, errFailed, err)
+
+	assert.Panics(t, func() {
+		pool.RunInTx(context.Background(), nil, func(tx *sql.Tx) error { panic("failed") })
+	})
+
+	assert.Equal(t, []string{"BEGIN", "COMMIT", "BEGIN", "ROLLBACK", "BEGIN", "ROLLBACK"}, transactions(rec))
+}
+
+func TestRunInTxRetry(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	pool := rec.DB()
+	defer pool.Close()
+
+	// Retried after a deadlock
+	var attempts int
+	err := pool.RunInTx(context.Background(), nil, func(tx *sql.Tx) error {
+		attempts++
+		if attempts < 3 {
+			return sqltest.ErrLockDeadlock
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	// Not retried after other errors
+	attempts = 0
+	errFailed := errors.New("failed")
+	err = pool.RunInTx(context.Background(), nil, func(tx *sql.Tx) error {
+		attempts++
+		return errFailed
+	})
+	assert.Equal(t, errFailed, err)
+	assert.Equal(t, 1, attempts)
+
+	// Not retried once the context is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts = 0
+	err = pool.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		attempts++
+		cancel()
+		return sqltest.ErrLockDeadlock
+	})
+	assert.Equal(t, sqltest.ErrLockDeadlock, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRunInTxMaxDuration(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	pool := rec.DB()
+	defer pool.Close()
+
+	var connectionID string
+	err := pool.RunInTx(context.Background(), &sql.TxOptions{MaxDuration: 10 * time.Millisecond}, func(tx *sql.Tx) error {
+		connectionID = rec.Statements()[0].ConnectionID
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+	assert.Equal(t, sql.ErrTxTimeout, err)
+	assert.True(t, rec.Killed(connectionID))
+}
+
+func TestRunInTxRollbackFailed(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^ROLLBACK$`).WillReturnError(errors.New("connection reset"))
+
+	var buf bytes.Buffer
+	pool := rec.DB()
+	pool.Logger = sql.NewSlogLogger(slog.NewTextHandler(&buf, nil))
+	defer pool.Close()
+
+	errFailed := errors.New("failed")
+	err := pool.RunInTx(context.Background(), nil, func(tx *sql.Tx) error { return errFailed })
+	assert.Equal(t, errFailed, err)
+	assert.Contains(t, buf.String(), `msg="rollback failed"`)
+	assert.Contains(t, buf.String(), `error="connection reset"`)
+}
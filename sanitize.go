@@ -0,0 +1,140 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"strings"
+)
+
+// SanitizeQuery replaces the string and numeric literals in query with a ? placeholder
+// so that the query can be recorded without exposing the values it contains.
+// Identifiers and comments are left unchanged.
+func SanitizeQuery(query string) string {
+
+	var out strings.Builder
+	out.Grow(len(query))
+
+	for _, l := range scanQuery(query) {
+		switch l.kind {
+		case lexString, lexNumber:
+			out.WriteByte('?')
+		default:
+			out.WriteString(l.text)
+		}
+	}
+
+	return out.String()
+}
+
+// Kinds of lexeme.
+const (
+	lexOther    = iota // A single character such as punctuation or ?
+	lexSpace           // Whitespace
+	lexComment         // /* ... */, # ... or -- ...
+	lexString          // '...' or "..."
+	lexNumber          // Numeric literal (including decimals, exponents and hexadecimals)
+	lexWord            // Identifier or keyword
+	lexQuoted          // Identifier quoted using backticks
+	lexOperator        // Comparison operator such as = or <=
+)
+
+// lexeme is a lexical element of a query.
+type lexeme struct {
+	kind int
+	text string
+}
+
+// scanQuery splits query into lexemes. Concatenating the text of
+// the lexemes reproduces query. Unterminated strings, quoted identifiers
+// and comments extend to the end of the query.
+func scanQuery(query string) []lexeme {
+	var lexemes []lexeme
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		kind, j := lexOther, i+1
+
+		switch {
+		case c == '\'' || c == '"':
+			// Quotes are escaped by doubling or with a backslash
+			kind = lexString
+			for ; j < len(query); j++ {
+				if query[j] == '\\' {
+					j++
+				} else if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						j++
+					} else {
+						j++
+						break
+					}
+				}
+			}
+		case c == '`':
+			// Backticks are escaped by doubling
+			kind = lexQuoted
+			for ; j < len(query); j++ {
+				if query[j] == '`' {
+					if j+1 < len(query) && query[j+1] == '`' {
+						j++
+					} else {
+						j++
+						break
+					}
+				}
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			kind, j = lexComment, len(query)
+			if k := strings.Index(query[i+2:], "*/"); k >= 0 {
+				j = i + 2 + k + 2
+			}
+		case c == '#' || strings.HasPrefix(query[i:], "-- "):
+			kind, j = lexComment, len(query)
+			if k := strings.IndexByte(query[i:], '\n'); k >= 0 {
+				j = i + k
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			kind = lexSpace
+			for j < len(query) && strings.IndexByte(" \t\n\r", query[j]) >= 0 {
+				j++
+			}
+		case isDigit(c):
+			kind = lexNumber
+			for ; j < len(query); j++ {
+				d := query[j]
+				if isIdentChar(d) || d == '.' {
+					continue
+				}
+				if (d == '+' || d == '-') && (query[j-1] == 'e' || query[j-1] == 'E') {
+					continue
+				}
+				break
+			}
+		case isIdentChar(c):
+			kind = lexWord
+			for j < len(query) && isIdentChar(query[j]) {
+				j++
+			}
+		case strings.IndexByte("<>=!", c) >= 0:
+			kind = lexOperator
+			for j < len(query) && strings.IndexByte("<>=!", query[j]) >= 0 {
+				j++
+			}
+		}
+
+		if j > len(query) {
+			j = len(query) // A trailing backslash
+		}
+		lexemes = append(lexemes, lexeme{kind: kind, text: query[i:j]})
+		i = j
+	}
+	return lexemes
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
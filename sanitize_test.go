@@ -0,0 +1,52 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT * FROM users WHERE id = 5", "SELECT * FROM users WHERE id = ?"},
+		{"SELECT * FROM t1 WHERE name = 'O''Brien' AND x = \"a\\\"b\"", "SELECT * FROM t1 WHERE name = ? AND x = ?"},
+		{"SELECT `col1` FROM `t2` LIMIT 10, 20", "SELECT `col1` FROM `t2` LIMIT ?, ?"},
+		{"/* app=api */ SELECT 1.5e-3, 0x1F", "/* app=api */ SELECT ?, ?"},
+		{"SELECT * FROM t WHERE id IN (?, ?)", "SELECT * FROM t WHERE id IN (?, ?)"},
+		{"SELECT 'unterminated", "SELECT ?"},
+		{"SELECT 1 -- id = 5\nFROM t # 'x'", "SELECT ? -- id = 5\nFROM t # 'x'"},
+		{"SELECT `a``1` FROM t WHERE x = 'a\\'", "SELECT `a``1` FROM t WHERE x = ?"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, SanitizeQuery(test.query), test.query)
+	}
+}
+
+func TestScanQuery(t *testing.T) {
+	queries := []string{
+		"SELECT `a``b`, 'c''d', \"e\\\"f\" FROM t WHERE x >= 1.5e+3 /* c */ -- d\n# e",
+		"SELECT 'unterminated\\",
+		"SELECT `unterminated",
+		"SELECT 1 /* unterminated",
+	}
+
+	for _, query := range queries {
+		var text string
+		for _, l := range scanQuery(query) {
+			text += l.text
+		}
+		assert.Equal(t, query, text)
+	}
+
+	var kinds []int
+	for _, l := range scanQuery("SELECT `a``b` FROM t WHERE x <= 10 AND y = 'z' /* c */") {
+		if l.kind != lexSpace {
+			kinds = append(kinds, l.kind)
+		}
+	}
+	assert.Equal(t, []int{lexWord, lexQuoted, lexWord, lexWord, lexWord, lexWord, lexOperator, lexNumber, lexWord, lexWord, lexOperator, lexString, lexComment}, kinds)
+}
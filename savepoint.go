@@ -0,0 +1,166 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	stdSql "database/sql"
+	"strconv"
+	"sync/atomic"
+)
+
+// Savepoint sets a named savepoint within the transaction.
+// If a savepoint with the same name already exists, it is replaced.
+//
+// If the context is canceled, the same KILL semantics as ExecContext apply.
+func (tx *Tx) Savepoint(ctx context.Context, name string) error {
+	_, err := tx.ExecContext(ctx, "SAVEPOINT "+quoteIdentifier(name))
+	return err
+}
+
+// RollbackTo rolls back the transaction to the named savepoint without
+// terminating the transaction. The savepoint is not released.
+//
+// If the context is canceled, the same KILL semantics as ExecContext apply.
+func (tx *Tx) RollbackTo(ctx context.Context, name string) error {
+	_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+quoteIdentifier(name))
+	return err
+}
+
+// Release removes the named savepoint. Changes made since the savepoint
+// remain part of the transaction.
+//
+// If the context is canceled, the same KILL semantics as ExecContext apply.
+func (tx *Tx) Release(ctx context.Context, name string) error {
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+quoteIdentifier(name))
+	return err
+}
+
+// BeginNested starts a nested transaction by setting an automatically named
+// savepoint.
+//
+// Committing the nested transaction releases the savepoint while rolling it back
+// undoes all changes made since the savepoint. The outer transaction is not
+// terminated in either case.
+func (tx *Tx) BeginNested(ctx context.Context) (*NestedTx, error) {
+	name := "sp_" + strconv.FormatUint(uint64(atomic.AddUint32(&tx.savepoints, 1)), 10)
+
+	err := tx.Savepoint(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &NestedTx{tx: tx, name: name}, nil
+}
+
+// NestedTx is a transaction nested inside another transaction using a savepoint.
+// Like Tx, it implements SQLNestableTx so that library code can start a
+// "sub-transaction" without knowing whether it is already nested.
+//
+// A nested transaction must end with a call to Commit or Rollback.
+//
+// After a call to Commit or Rollback, Commit and Rollback fail with ErrTxDone.
+// Other operations continue to run on the outer transaction.
+type NestedTx struct {
+	tx   *Tx
+	name string
+	done int32
+}
+
+// Name returns the name of the savepoint.
+func (n *NestedTx) Name() string {
+	return n.name
+}
+
+// BeginNested starts a transaction nested inside this nested transaction.
+func (n *NestedTx) BeginNested(ctx context.Context) (*NestedTx, error) {
+	return n.tx.BeginNested(ctx)
+}
+
+// Commit releases the savepoint.
+func (n *NestedTx) Commit() error {
+	return n.CommitContext(context.Background())
+}
+
+// CommitContext releases the savepoint.
+//
+// If the context is canceled, the same KILL semantics as ExecContext apply.
+func (n *NestedTx) CommitContext(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&n.done, 0, 1) {
+		return stdSql.ErrTxDone
+	}
+	return n.tx.Release(ctx, n.name)
+}
+
+// Rollback undoes all changes made since the savepoint and then releases it.
+func (n *NestedTx) Rollback() error {
+	return n.RollbackContext(context.Background())
+}
+
+// RollbackContext undoes all changes made since the savepoint and then releases it.
+//
+// If the context is canceled, the same KILL semantics as ExecContext apply.
+func (n *NestedTx) RollbackContext(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&n.done, 0, 1) {
+		return stdSql.ErrTxDone
+	}
+
+	err := n.tx.RollbackTo(ctx, n.name)
+	if err != nil {
+		return err
+	}
+	return n.tx.Release(ctx, n.name)
+}
+
+// Exec executes a query that doesn't return rows.
+// For example: an INSERT and UPDATE.
+func (n *NestedTx) Exec(query string, args ...interface{}) (stdSql.Result, error) {
+	return n.tx.Exec(query, args...)
+}
+
+// ExecContext executes a query that doesn't return rows.
+// For example: an INSERT and UPDATE.
+func (n *NestedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (stdSql.Result, error) {
+	return n.tx.ExecContext(ctx, query, args...)
+}
+
+// Prepare creates a prepared statement for use within the outer transaction.
+func (n *NestedTx) Prepare(query string) (*Stmt, error) {
+	return n.tx.Prepare(query)
+}
+
+// PrepareContext creates a prepared statement for use within the outer transaction.
+func (n *NestedTx) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	return n.tx.PrepareContext(ctx, query)
+}
+
+// Query executes a query that returns rows, typically a SELECT.
+func (n *NestedTx) Query(query string, args ...interface{}) (*Rows, error) {
+	return n.tx.Query(query, args...)
+}
+
+// QueryContext executes a query that returns rows, typically a SELECT.
+func (n *NestedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	return n.tx.QueryContext(ctx, query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row.
+func (n *NestedTx) QueryRow(query string, args ...interface{}) *Row {
+	return n.tx.QueryRow(query, args...)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row.
+func (n *NestedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	return n.tx.QueryRowContext(ctx, query, args...)
+}
+
+// Stmt returns a transaction-specific prepared statement from
+// an existing statement.
+func (n *NestedTx) Stmt(stmt *stdSql.Stmt) *Stmt {
+	return n.tx.Stmt(stmt)
+}
+
+// StmtContext returns a transaction-specific prepared statement from
+// an existing statement.
+func (n *NestedTx) StmtContext(ctx context.Context, stmt *stdSql.Stmt) *Stmt {
+	return n.tx.StmtContext(ctx, stmt)
+}
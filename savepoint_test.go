@@ -0,0 +1,96 @@
+package sql_test
+
+import (
+	"context"
+	stdSql "database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+// savepoints returns the savepoint statements recorded by rec.
+func savepoints(rec *sqltest.Recorder) []string {
+	var queries []string
+	for _, s := range rec.Statements() {
+		if strings.Contains(s.Query, "SAVEPOINT") {
+			queries = append(queries, s.Query)
+		}
+	}
+	return queries
+}
+
+func TestSavepoint(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	tx := beginTx(t, pool)
+	defer tx.Rollback()
+
+	require.NoError(t, tx.Savepoint(ctx, "before_update"))
+	require.NoError(t, tx.RollbackTo(ctx, "before_update"))
+	require.NoError(t, tx.Release(ctx, "before_update"))
+
+	// Backticks in the name are escaped
+	require.NoError(t, tx.Savepoint(ctx, "a`; DROP TABLE users; --"))
+
+	assert.Equal(t, []string{
+		"SAVEPOINT `before_update`",
+		"ROLLBACK TO SAVEPOINT `before_update`",
+		"RELEASE SAVEPOINT `before_update`",
+		"SAVEPOINT `a``; DROP TABLE users; --`",
+	}, savepoints(rec))
+}
+
+func TestBeginNested(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	tx := beginTx(t, pool)
+
+	// nested is library code that does not know whether it is given
+	// the outer transaction or a nested one.
+	nested := func(parent sql.SQLNestableTx, commit bool) *sql.NestedTx {
+		n, err := parent.BeginNested(ctx)
+		require.NoError(t, err)
+		if commit {
+			require.NoError(t, n.Commit())
+		} else {
+			require.NoError(t, n.Rollback())
+		}
+		return n
+	}
+
+	outer, err := tx.BeginNested(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "sp_1", outer.Name())
+
+	assert.Equal(t, "sp_2", nested(outer, true).Name())
+	n := nested(tx, false)
+	assert.Equal(t, "sp_3", n.Name())
+
+	// A nested transaction can only be ended once
+	assert.Equal(t, stdSql.ErrTxDone, n.Commit())
+	assert.Equal(t, stdSql.ErrTxDone, n.Rollback())
+
+	require.NoError(t, outer.Commit())
+	require.NoError(t, tx.Commit())
+
+	assert.Equal(t, []string{
+		"SAVEPOINT `sp_1`",
+		"SAVEPOINT `sp_2`",
+		"RELEASE SAVEPOINT `sp_2`",
+		"SAVEPOINT `sp_3`",
+		"ROLLBACK TO SAVEPOINT `sp_3`",
+		"RELEASE SAVEPOINT `sp_3`",
+		"RELEASE SAVEPOINT `sp_1`",
+	}, savepoints(rec))
+}
@@ -0,0 +1,264 @@
+package sql_test
+
+import (
+	"context"
+	stdSql "database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+type timestamps struct {
+	CreatedAt time.Time
+}
+
+type Audit struct {
+	UpdatedBy string
+}
+
+type account struct {
+	timestamps
+	*Audit
+	UserID   int64
+	Nickname *string
+	Email    stdSql.NullString `db:"email_address"`
+	Secret   string            `db:"-"`
+}
+
+func TestScanStruct(t *testing.T) {
+	created := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT`).WillReturnRows([]string{"user_id", "nickname", "EMAIL_ADDRESS", "created_at", "updated_by"},
+		[]interface{}{1, "al", "alice@example.com", created, "admin"},
+		[]interface{}{2, nil, nil, created, "bob"},
+	)
+	rec.Expect(`^DELETE`).WillReturnRows([]string{"secret"})
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, "SELECT * FROM accounts")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var accounts []account
+	for rows.Next() {
+		var a account
+		require.NoError(t, rows.ScanStruct(&a))
+		accounts = append(accounts, a)
+	}
+	require.NoError(t, rows.Err())
+	require.Len(t, accounts, 2)
+
+	nickname := "al"
+	assert.Equal(t, account{
+		timestamps: timestamps{created},
+		Audit:      &Audit{"admin"},
+		UserID:     1,
+		Nickname:   &nickname,
+		Email:      stdSql.NullString{String: "alice@example.com", Valid: true},
+	}, accounts[0])
+	assert.Nil(t, accounts[1].Nickname)
+	assert.False(t, accounts[1].Email.Valid)
+
+	var a account
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT * FROM accounts WHERE user_id = ?", 1).ScanStruct(&a))
+	assert.Equal(t, int64(1), a.UserID)
+
+	err = conn.QueryRowContext(ctx, "DELETE FROM accounts RETURNING secret").ScanStruct(&a)
+	assert.EqualError(t, err, `sql: no field for column "secret" in sql_test.account`)
+
+	err = conn.QueryRowContext(ctx, "SELECT * FROM accounts").ScanStruct(a)
+	assert.EqualError(t, err, "sql: destination must be a non-nil pointer to a struct, not sql_test.account")
+}
+
+func TestRowScanNoRows(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT name`).WillReturnRows([]string{"name"})
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var name string
+	assert.Equal(t, stdSql.ErrNoRows, conn.QueryRowContext(ctx, "SELECT name FROM users").Scan(&name))
+
+	var raw stdSql.RawBytes
+	assert.EqualError(t, conn.QueryRowContext(ctx, "SELECT name FROM users").Scan(&raw), "sql: RawBytes isn't allowed on Row.Scan")
+}
+
+func TestScanMap(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT`).WillReturnRows([]string{"id", "name", "email"}, []interface{}{1, "alice", nil})
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, "SELECT id, name, email FROM users")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	require.True(t, rows.Next())
+	m, err := rows.ScanMap()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": int64(1), "name": "alice", "email": nil}, m)
+
+	s, err := rows.ScanSlice()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(1), "alice", nil}, s)
+}
+
+func TestScanMapColumnTypes(t *testing.T) {
+	created := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	server.Handle(`^SELECT price, doc, flags, stock, created, sku FROM products`, sqltest.Response{
+		Columns:     []string{"price", "doc", "flags", "stock", "created", "sku"},
+		ColumnTypes: []string{"DECIMAL", "JSON", "BIT", "UNSIGNED INT", "DATETIME", "VARBINARY"},
+		Rows: [][]interface{}{
+			{"12345678901234567890.12", `{"a":1}`, []byte{0x01, 0x02}, 7, created, []byte{0xff}},
+			{nil, nil, nil, nil, nil, nil},
+		},
+	})
+
+	dbStd, err := stdSql.Open("mysql", server.DSN()+"&parseTime=true")
+	require.NoError(t, err)
+	pool := &sql.DB{DB: dbStd}
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, "SELECT price, doc, flags, stock, created, sku FROM products")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	cts, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	var names []string
+	for _, ct := range cts {
+		names = append(names, ct.DatabaseTypeName())
+	}
+	assert.Equal(t, []string{"DECIMAL", "JSON", "BIT", "UNSIGNED INT", "DATETIME", "VARBINARY"}, names)
+
+	require.True(t, rows.Next())
+	m, err := rows.ScanMap()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"price":   "12345678901234567890.12",
+		"doc":     json.RawMessage(`{"a":1}`),
+		"flags":   uint64(258),
+		"stock":   uint64(7),
+		"created": created,
+		"sku":     []byte{0xff},
+	}, m)
+
+	require.True(t, rows.Next())
+	s, err := rows.ScanSlice()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{nil, nil, nil, nil, nil, nil}, s)
+
+	assert.False(t, rows.Next())
+	require.NoError(t, rows.Err())
+}
+
+func TestScanStructCancel(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT user_id, nickname FROM accounts`).WillReturnRows([]string{"user_id", "nickname"},
+		[]interface{}{1, "al"},
+		[]interface{}{2, "bo"},
+	)
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var connectionID string
+	require.NoError(t, conn.QueryRowContext(context.Background(), "SELECT CONNECTION_ID()").Scan(&connectionID))
+
+	t.Run("Rows", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		rows, err := conn.QueryContext(ctx, "SELECT user_id, nickname FROM accounts")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		require.True(t, rows.Next())
+		cancel()
+
+		var a account
+		rows.ScanStruct(&a)
+		assert.True(t, rec.Killed(connectionID))
+	})
+
+	t.Run("Row", func(t *testing.T) {
+		before := len(rec.Kills())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		row := conn.QueryRowContext(ctx, "SELECT user_id, nickname FROM accounts")
+		cancel()
+
+		// database/sql closes the rows asynchronously, so the row may still be scanned
+		var a account
+		row.ScanStruct(&a)
+		assert.Len(t, rec.Kills(), before+1)
+	})
+}
+
+func TestQueryRowErrorIntercepted(t *testing.T) {
+	failed := errors.New("table doesn't exist")
+
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT name FROM missing`).WillReturnError(failed)
+
+	var intercepted []error
+	pool := rec.DB()
+	pool.Interceptors = []sql.Interceptor{
+		func(ctx context.Context, call *sql.Call, next sql.Handler) error {
+			err := next(ctx, call)
+			if call.Op == sql.OpQueryRow {
+				intercepted = append(intercepted, err)
+			}
+			return err
+		},
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The interceptor sees the error before Scan is called
+	row := conn.QueryRowContext(ctx, "SELECT name FROM missing")
+	assert.Equal(t, []error{failed}, intercepted)
+
+	var name string
+	assert.Equal(t, failed, row.Scan(&name))
+}
@@ -0,0 +1,140 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	stdSql "database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// ScanMap copies the columns in the current row into a map keyed by column name.
+// If several columns have the same name, the last one is used.
+// See ScanSlice for how the values are converted.
+func (rs *Rows) ScanMap() (map[string]interface{}, error) {
+	cts, err := rs.cachedColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	vals, err := rs.ScanSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{}, len(cts))
+	for i, ct := range cts {
+		m[ct.Name()] = vals[i]
+	}
+	return m, nil
+}
+
+// ScanSlice copies the columns in the current row into a slice.
+// Values are converted based on the column's database type:
+//
+//	NULL                                    nil
+//	TINYINT, SMALLINT, INT, BIGINT, YEAR    int64 (uint64 if UNSIGNED)
+//	FLOAT, DOUBLE                           float64
+//	DECIMAL                                 string (to avoid losing precision)
+//	DATE, DATETIME, TIMESTAMP               time.Time if parseTime is set, otherwise string
+//	TIME                                    string
+//	JSON                                    json.RawMessage
+//	BIT                                     uint64
+//	BLOB, BINARY, VARBINARY, GEOMETRY       []byte
+//	CHAR, VARCHAR, TEXT, ENUM, SET, etc.    string
+func (rs *Rows) ScanSlice() ([]interface{}, error) {
+	cts, err := rs.cachedColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	vals := make([]interface{}, len(cts))
+	targets := make([]interface{}, len(cts))
+	for i := range vals {
+		targets[i] = &vals[i]
+	}
+
+	// Scan copies []byte values scanned into *interface{}
+	if err := rs.Scan(targets...); err != nil {
+		return nil, err
+	}
+
+	for i, ct := range cts {
+		v, err := convertColumn(ct.DatabaseTypeName(), vals[i])
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+// cachedColumnTypes returns the column types of the current result set.
+// They are only fetched once per result set.
+func (rs *Rows) cachedColumnTypes() ([]*stdSql.ColumnType, error) {
+	if rs.columnTypes == nil {
+		cts, err := rs.rows.ColumnTypes()
+		if err != nil {
+			return nil, err
+		}
+		rs.columnTypes = cts
+	}
+	return rs.columnTypes, nil
+}
+
+// convertColumn converts a value provided by the driver for a column of the
+// given database type (as reported by ColumnType's DatabaseTypeName).
+func convertColumn(typeName string, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	unsigned := strings.HasPrefix(typeName, "UNSIGNED ")
+	typeName = strings.TrimPrefix(typeName, "UNSIGNED ")
+
+	b, isBytes := v.([]byte)
+
+	switch typeName {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR":
+		switch x := v.(type) {
+		case []byte:
+			if unsigned {
+				return strconv.ParseUint(string(x), 10, 64)
+			}
+			return strconv.ParseInt(string(x), 10, 64)
+		case int64:
+			if unsigned {
+				return uint64(x), nil
+			}
+		}
+	case "FLOAT", "DOUBLE":
+		switch x := v.(type) {
+		case []byte:
+			return strconv.ParseFloat(string(x), 64)
+		case float32:
+			return float64(x), nil
+		}
+	case "JSON":
+		if isBytes {
+			return json.RawMessage(b), nil
+		}
+	case "BIT":
+		if isBytes {
+			var n uint64
+			for _, c := range b {
+				n = n<<8 | uint64(c)
+			}
+			return n, nil
+		}
+	case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "GEOMETRY":
+		return v, nil
+	default:
+		// DECIMAL, date and time types (when parseTime is not set), CHAR, VARCHAR,
+		// TEXT, ENUM, SET and types not reported by the driver
+		if isBytes {
+			return string(b), nil
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,47 @@
+package sql
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertColumn(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		typeName string
+		in       interface{}
+		expected interface{}
+	}{
+		{"BIGINT", nil, nil},
+		{"BIGINT", []byte("-42"), int64(-42)},
+		{"BIGINT", int64(-42), int64(-42)},
+		{"UNSIGNED BIGINT", []byte("18446744073709551615"), uint64(18446744073709551615)},
+		{"UNSIGNED INT", int64(42), uint64(42)},
+		{"YEAR", []byte("2019"), int64(2019)},
+		{"DOUBLE", []byte("1.5"), 1.5},
+		{"FLOAT", float32(1.5), 1.5},
+		{"DECIMAL", []byte("12345678901234567890.12"), "12345678901234567890.12"},
+		{"DATETIME", []byte("2019-01-02 03:04:05"), "2019-01-02 03:04:05"},
+		{"DATETIME", now, now},
+		{"TIME", []byte("838:59:59"), "838:59:59"},
+		{"JSON", []byte(`{"a":1}`), json.RawMessage(`{"a":1}`)},
+		{"BIT", []byte{0x01, 0x02}, uint64(258)},
+		{"VARBINARY", []byte{0xff}, []byte{0xff}},
+		{"VARCHAR", []byte("alice"), "alice"},
+		{"", []byte("alice"), "alice"},
+	}
+
+	for _, tt := range tests {
+		v, err := convertColumn(tt.typeName, tt.in)
+		require.NoError(t, err, tt.typeName)
+		assert.Equal(t, tt.expected, v, tt.typeName)
+	}
+
+	_, err := convertColumn("INT", []byte("abc"))
+	assert.Error(t, err)
+}
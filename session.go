@@ -0,0 +1,240 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	stdSql "database/sql"
+	"runtime"
+	"time"
+)
+
+// session holds what is required to run an operation on a single connection
+// and to kill it when the context is canceled. It is shared by Conn, Tx, Stmt,
+// Rows and Row.
+type session struct {
+	db           *DB
+	killerPool   StdSQLDB
+	connectionID string
+	kto          time.Duration
+
+	// Tag comment of the query being run (Rows and Row) or of the query
+	// the statement was prepared with (Stmt)
+	comment string
+}
+
+// tag prepends the tag comment to the call's query and returns the comment.
+// Prepared statements are tagged when they are prepared.
+func (s *session) tag(ctx context.Context, call *Call) string {
+	if call.Stmt {
+		return s.comment
+	}
+	comment := s.db.comment(ctx)
+	call.Query = tagQuery(comment, call.Query)
+	return comment
+}
+
+// runExec runs an operation that does not return rows through the interceptors.
+//
+// If the context is canceled before the operation returns, a KILL signal is
+// sent and the context's error is returned immediately.
+func (s *session) runExec(ctx context.Context, call *Call, exec func(ctx context.Context, call *Call) (stdSql.Result, error)) (stdSql.Result, error) {
+
+	call.Op = OpExec
+	call.ConnectionID = s.connectionID
+	comment := s.tag(ctx, call)
+
+	// Retained because the session may be unleaked while the operation runs
+	killerPool, connectionID, kto := s.killerPool, s.connectionID, s.kto
+
+	err := s.db.intercept(ctx, call, func(ctx context.Context, call *Call) error {
+
+		// Create a context that is used to cancel exec()
+		cancelCtx, cancelFunc := context.WithCancel(context.Background())
+		defer cancelFunc()
+
+		outChan := make(chan stdSql.Result, 1)
+		errChan := make(chan error, 1)
+		killedChan := make(chan error, 1)
+		returnedChan := make(chan struct{}) // Used to indicate that this function has returned
+		defer close(returnedChan)
+
+		killer := s.db.trackGoroutine(connectionID, call.Query)
+		go func() {
+			defer s.db.untrack(killer)
+			select {
+			case <-ctx.Done():
+				// context has been canceled
+				s.db.kill(ctx, killerPool, connectionID, kto, comment)
+				killedChan <- ctx.Err()
+			case <-returnedChan:
+			}
+		}()
+
+		execer := s.db.trackGoroutine(connectionID, call.Query)
+		go func() {
+			defer s.db.untrack(execer)
+			res, err := exec(cancelCtx, call)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			outChan <- res
+		}()
+
+		select {
+		case err := <-killedChan:
+			call.Killed = true
+			return err
+		case err := <-errChan:
+			if ctx.Err() != nil {
+				// The KILL signal may have interrupted exec before the
+				// context's error was reported
+				err = <-killedChan
+				call.Killed = true
+			}
+			return err
+		case out := <-outChan:
+			call.Result = out
+			return nil
+		}
+	})
+	if err != nil {
+		s.db.noteErr(err)
+		return nil, err
+	}
+	return call.Result, nil
+}
+
+// runQuery runs an operation that returns rows through the interceptors.
+//
+// If the context is canceled, a KILL signal is sent.
+func (s *session) runQuery(ctx context.Context, call *Call, query func(ctx context.Context, call *Call) (*stdSql.Rows, error)) (*Rows, error) {
+
+	call.Op = OpQuery
+	call.ConnectionID = s.connectionID
+	comment := s.tag(ctx, call)
+
+	var rows *Rows
+
+	err := s.db.intercept(ctx, call, func(ctx context.Context, call *Call) error {
+
+		// We can't use the same approach used in runExec because defer cancelFunc()
+		// cancels rows.Scan.
+		defer func() {
+			if ctx.Err() != nil {
+				if rows != nil {
+					rows.kill()
+				} else {
+					s.db.kill(ctx, s.killerPool, s.connectionID, s.kto, comment)
+				}
+				call.Killed = true
+			}
+		}()
+
+		rs, err := query(ctx, call)
+		if err != nil {
+			return err
+		}
+		rows = &Rows{session: *s, ctx: ctx, rows: rs, query: call.Query, call: call}
+		rows.comment = comment
+		return nil
+	})
+	if err != nil {
+		if rows != nil {
+			// An interceptor failed after the query was run
+			rows.rows.Close()
+		}
+		s.db.noteErr(err)
+		return nil, err
+	}
+	if s.db != nil && s.db.Logger != nil {
+		runtime.SetFinalizer(rows, (*Rows).leaked)
+	}
+	s.db.trackRows(rows)
+	return rows, nil
+}
+
+// runQueryRow runs an operation that is expected to return at most one row
+// through the interceptors. Errors are returned to the interceptors and by Row's Scan method.
+// The rows are retained (instead of a *stdSql.Row) so that the columns are available.
+//
+// If the context is canceled, a KILL signal is sent.
+func (s *session) runQueryRow(ctx context.Context, call *Call, query func(ctx context.Context, call *Call) (*stdSql.Rows, error)) *Row {
+
+	call.Op = OpQueryRow
+	call.ConnectionID = s.connectionID
+	comment := s.tag(ctx, call)
+
+	row := &Row{session: *s, ctx: ctx, call: call}
+	row.comment = comment
+
+	row.err = s.db.intercept(ctx, call, func(ctx context.Context, call *Call) error {
+
+		// As with runQuery, the KILL signal can't be sent using a cancelable
+		// context because canceling it would cancel Row's Scan.
+		row.ctx = ctx
+		defer func() {
+			if ctx.Err() != nil {
+				row.kill()
+				call.Killed = true
+			}
+		}()
+
+		rs, err := query(ctx, call)
+		if err != nil {
+			return err
+		}
+		row.rows = rs
+		return nil
+	})
+	if row.err != nil && row.rows != nil {
+		// An interceptor failed after the query was run
+		row.rows.Close()
+	}
+	s.db.noteErr(row.err)
+	return row
+}
+
+// runPrepare runs an operation that creates a prepared statement through the interceptors.
+func (s *session) runPrepare(ctx context.Context, call *Call, prepare func(ctx context.Context, call *Call) (*stdSql.Stmt, error)) (*Stmt, error) {
+
+	call.Op = OpPrepare
+	call.ConnectionID = s.connectionID
+	comment := s.tag(ctx, call)
+
+	var stmt *Stmt
+
+	err := s.db.intercept(ctx, call, func(ctx context.Context, call *Call) error {
+		// You can not cancel a Prepare.
+		// See: https://github.com/rocketlaunchr/mysql-go/issues/3
+		st, err := prepare(ctx, call)
+		if err != nil {
+			return err
+		}
+		stmt = &Stmt{session: *s, stmt: st, query: call.Query}
+		stmt.comment = comment
+		return nil
+	})
+	if err != nil {
+		if stmt != nil {
+			// An interceptor failed after the statement was prepared
+			stmt.stmt.Close()
+		}
+		return nil, err
+	}
+	if s.db != nil && s.db.Logger != nil {
+		runtime.SetFinalizer(stmt, (*Stmt).leaked)
+	}
+	s.db.trackStmt(stmt)
+	return stmt, nil
+}
+
+// run runs an operation (such as Begin, Commit or Rollback) that does not
+// involve a query through the interceptors.
+func (s *session) run(ctx context.Context, op Op, fn func(ctx context.Context) error) error {
+	call := &Call{Op: op, ConnectionID: s.connectionID}
+	return s.db.intercept(ctx, call, func(ctx context.Context, call *Call) error {
+		return fn(ctx)
+	})
+}
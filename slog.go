@@ -0,0 +1,34 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// NewSlogLogger returns a Logger that writes records to h.
+//
+// Example:
+//
+//	pool.Logger = sql.NewSlogLogger(slog.NewJSONHandler(os.Stderr, nil))
+func NewSlogLogger(h slog.Handler) Logger {
+	return slogLogger{h}
+}
+
+type slogLogger struct {
+	h slog.Handler
+}
+
+func (l slogLogger) Enabled(ctx context.Context, level LogLevel) bool {
+	return l.h.Enabled(ctx, slog.Level(level))
+}
+
+func (l slogLogger) Log(ctx context.Context, level LogLevel, msg string, attrs ...LogAttr) {
+	r := slog.NewRecord(time.Now(), slog.Level(level), msg, 0)
+	for _, a := range attrs {
+		r.AddAttrs(slog.Any(a.Key, a.Value))
+	}
+	l.h.Handle(ctx, r)
+}
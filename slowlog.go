@@ -0,0 +1,183 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SlowQuery describes a query reported by SlowQueryLog.
+type SlowQuery struct {
+	Op           Op
+	Query        string
+	Args         []interface{}
+	ConnectionID string
+	Duration     time.Duration
+	Killed       bool
+	Err          error
+
+	// Plan is the output of EXPLAIN FORMAT=JSON.
+	// It is only populated for SELECT queries if SlowQueryLog.Explain is set.
+	Plan       string
+	ExplainErr error
+}
+
+// ErrExplainSkipped is set as SlowQuery.ExplainErr when EXPLAIN is not run
+// because MaxExplains EXPLAIN queries are already running.
+var ErrExplainSkipped = errors.New("sql: too many EXPLAIN queries running")
+
+// SlowQueryLog reports queries run on a Conn, Tx or Stmt that take longer than
+// a threshold. Queries that are killed are always reported.
+//
+// For Query and QueryRow operations, the duration is the time taken for the query
+// to start returning rows. They are reported once the rows are closed, so that
+// queries killed while the rows are being read are also reported.
+//
+// Example:
+//
+//	diagPool, _ := stdSql.Open("mysql", dsn)
+//	diagPool.SetMaxOpenConns(2)
+//
+//	slowLog := &sql.SlowQueryLog{
+//	   Threshold: time.Second,
+//	   Explain:   true,
+//	   Pool:      diagPool,
+//	   Report:    func(q sql.SlowQuery) { log.Println(q.Duration, q.Query, q.Plan) },
+//	}
+//	pool.Interceptors = append(pool.Interceptors, slowLog.Intercept)
+type SlowQueryLog struct {
+
+	// Threshold is how long a query can run before it is reported.
+	Threshold time.Duration
+
+	// Explain sets whether EXPLAIN FORMAT=JSON is run for reported SELECT queries.
+	Explain bool
+
+	// Pool is used to run EXPLAIN. It should be a dedicated diagnostics pool so
+	// that it is not starved by the queries being investigated. Using the KillerPool
+	// is not recommended since EXPLAIN would delay KILL signals.
+	// It is required if Explain is set.
+	Pool StdSQLDB
+
+	// ExplainTimeout is the maximum time EXPLAIN can run for.
+	// A value of zero defaults to 5 seconds.
+	ExplainTimeout time.Duration
+
+	// MaxExplains is the maximum number of EXPLAIN queries run concurrently.
+	// Slow queries reported while the limit is reached are reported without a plan
+	// and with ExplainErr set to ErrExplainSkipped. A value of zero defaults to 4.
+	MaxExplains int
+
+	explainsOnce sync.Once
+	explains     chan struct{}
+
+	// Report is called for each slow or killed query. When a plan is captured,
+	// Report is called from a separate goroutine so that the caller is not delayed.
+	Report func(q SlowQuery)
+}
+
+// Intercept is an Interceptor that reports slow and killed queries.
+func (l *SlowQueryLog) Intercept(ctx context.Context, call *Call, next Handler) error {
+
+	if call.Op != OpExec && call.Op != OpQuery && call.Op != OpQueryRow {
+		return next(ctx, call)
+	}
+
+	start := time.Now()
+	err := next(ctx, call)
+	duration := time.Since(start)
+
+	// Queries can still be killed while their rows are being read
+	call.OnClose(func() {
+		l.report(call, duration, err)
+	})
+	return err
+}
+
+// report reports the call if it is slow or was killed.
+func (l *SlowQueryLog) report(call *Call, duration time.Duration, err error) {
+
+	if l.Report == nil || (duration < l.Threshold && !call.Killed) {
+		return
+	}
+
+	q := SlowQuery{
+		Op:           call.Op,
+		Query:        call.Query,
+		Args:         append([]interface{}(nil), call.Args...),
+		ConnectionID: call.ConnectionID,
+		Duration:     duration,
+		Killed:       call.Killed,
+		Err:          err,
+	}
+
+	if !l.Explain || l.Pool == nil || !isSelect(q.Query) {
+		l.Report(q)
+		return
+	}
+
+	l.explainsOnce.Do(func() {
+		max := l.MaxExplains
+		if max <= 0 {
+			max = 4
+		}
+		l.explains = make(chan struct{}, max)
+	})
+
+	select {
+	case l.explains <- struct{}{}:
+	default:
+		q.ExplainErr = ErrExplainSkipped
+		l.Report(q)
+		return
+	}
+
+	go func() {
+		defer func() { <-l.explains }()
+		q.Plan, q.ExplainErr = l.explain(q.Query, q.Args)
+		l.Report(q)
+	}()
+}
+
+// explain runs EXPLAIN FORMAT=JSON for query.
+func (l *SlowQueryLog) explain(query string, args []interface{}) (string, error) {
+
+	timeout := l.ExplainTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+	defer cancelFunc()
+
+	var plan string
+	err := l.Pool.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query, args...).Scan(&plan)
+	return plan, err
+}
+
+// isSelect reports whether query is a SELECT statement, ignoring leading
+// whitespace and comments.
+func isSelect(query string) bool {
+	for {
+		query = strings.TrimSpace(query)
+		if !strings.HasPrefix(query, "/*") {
+			break
+		}
+		end := strings.Index(query, "*/")
+		if end == -1 {
+			return false
+		}
+		query = query[end+2:]
+	}
+
+	return hasPrefixFold(query, "SELECT") || hasPrefixFold(query, "WITH")
+}
+
+// hasPrefixFold reports whether s begins with prefix, ignoring case.
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
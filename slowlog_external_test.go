@@ -0,0 +1,103 @@
+package sql_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+func TestSlowQueryLogKilledWhileScanning(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT name FROM users`).WillReturnRows([]string{"name"}, []interface{}{"alice"}, []interface{}{"bob"})
+
+	var reported []sql.SlowQuery
+	slowLog := &sql.SlowQueryLog{
+		Threshold: time.Hour,
+		Report:    func(q sql.SlowQuery) { reported = append(reported, q) },
+	}
+
+	pool := rec.DB()
+	defer pool.Close()
+	pool.Interceptors = []sql.Interceptor{slowLog.Intercept}
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Not reported since the query was neither slow nor killed
+	rows, err := conn.QueryContext(context.Background(), "SELECT name FROM users")
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	assert.Empty(t, reported)
+
+	// Killed while the rows are being read
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err = conn.QueryContext(ctx, "SELECT name FROM users")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	assert.Empty(t, reported)
+
+	cancel()
+	rows.Close()
+
+	if assert.Len(t, reported, 1) {
+		assert.Equal(t, "SELECT name FROM users", reported[0].Query)
+		assert.True(t, reported[0].Killed)
+	}
+}
+
+func TestSlowQueryLogMaxExplains(t *testing.T) {
+	diag := sqltest.NewRecorder()
+	diag.Expect(`^EXPLAIN`).WillDelayFor(100*time.Millisecond).WillReturnRows([]string{"EXPLAIN"}, []interface{}{`{"query_block":{}}`})
+	diagPool := diag.DB()
+	defer diagPool.Close()
+
+	var (
+		lock     sync.Mutex
+		wg       sync.WaitGroup
+		reported []sql.SlowQuery
+	)
+	wg.Add(2)
+
+	slowLog := &sql.SlowQueryLog{
+		Explain:     true,
+		Pool:        diagPool.DB,
+		MaxExplains: 1,
+		Report: func(q sql.SlowQuery) {
+			lock.Lock()
+			reported = append(reported, q)
+			lock.Unlock()
+			wg.Done()
+		},
+	}
+
+	pool := sqltest.NewRecorder().DB()
+	defer pool.Close()
+	pool.Interceptors = []sql.Interceptor{slowLog.Intercept}
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for i := 0; i < 2; i++ {
+		rows, err := conn.QueryContext(context.Background(), "SELECT 1")
+		require.NoError(t, err)
+		rows.Close()
+	}
+	wg.Wait()
+
+	// The second plan is not captured while the first EXPLAIN is running
+	if assert.Len(t, reported, 2) {
+		assert.Equal(t, sql.ErrExplainSkipped, reported[0].ExplainErr)
+		assert.NoError(t, reported[1].ExplainErr)
+		assert.Equal(t, `{"query_block":{}}`, reported[1].Plan)
+	}
+}
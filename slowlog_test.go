@@ -0,0 +1,49 @@
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	var reported []SlowQuery
+
+	slowLog := &SlowQueryLog{
+		Threshold: 20 * time.Millisecond,
+		Report:    func(q SlowQuery) { reported = append(reported, q) },
+	}
+	db := &DB{Interceptors: []Interceptor{slowLog.Intercept}}
+
+	run := func(call *Call, d time.Duration, killed bool) {
+		db.intercept(context.Background(), call, func(ctx context.Context, call *Call) error {
+			time.Sleep(d)
+			call.Killed = killed
+			return nil
+		})
+		call.close() // The rows are closed
+	}
+
+	run(&Call{Op: OpQuery, Query: "SELECT 1"}, 0, false)
+	run(&Call{Op: OpQuery, Query: "SELECT SLEEP(1)"}, 30*time.Millisecond, false)
+	run(&Call{Op: OpExec, Query: "UPDATE t SET a = 1"}, 0, true)
+	run(&Call{Op: OpBegin}, 30*time.Millisecond, false)
+
+	if assert.Len(t, reported, 2) {
+		assert.Equal(t, "SELECT SLEEP(1)", reported[0].Query)
+		assert.True(t, reported[0].Duration >= 30*time.Millisecond)
+		assert.Equal(t, "UPDATE t SET a = 1", reported[1].Query)
+		assert.True(t, reported[1].Killed)
+	}
+}
+
+func TestIsSelect(t *testing.T) {
+	assert.True(t, isSelect("SELECT 1"))
+	assert.True(t, isSelect("  select 1"))
+	assert.True(t, isSelect("/* app=x */ SELECT 1"))
+	assert.True(t, isSelect("WITH t AS (SELECT 1) SELECT * FROM t"))
+	assert.False(t, isSelect("UPDATE t SET a = 1"))
+	assert.False(t, isSelect("/* unterminated SELECT"))
+}
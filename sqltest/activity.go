@@ -0,0 +1,67 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sqltest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// errQueryInterrupted is returned for a query that was killed.
+var errQueryInterrupted = &mysql.MySQLError{Number: 1317, SQLState: [5]byte{'7', '0', '1', '0', '0'}, Message: "Query execution was interrupted"}
+
+// activity tracks the query a connection is running so that it can be killed.
+type activity struct {
+	lock      sync.Mutex
+	interrupt chan struct{} // Closed to interrupt the running query (nil when idle)
+	query     string
+	since     time.Time
+}
+
+// wait blocks for d while running query. It reports whether the query was killed.
+// If ctx is done first, its error is returned.
+func (a *activity) wait(ctx context.Context, query string, d time.Duration) (bool, error) {
+	interrupt := make(chan struct{})
+
+	a.lock.Lock()
+	a.interrupt, a.query, a.since = interrupt, query, time.Now()
+	a.lock.Unlock()
+
+	defer func() {
+		a.lock.Lock()
+		a.interrupt, a.query = nil, ""
+		a.lock.Unlock()
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return false, nil
+	case <-interrupt:
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// kill interrupts the running query.
+func (a *activity) kill() {
+	a.lock.Lock()
+	if a.interrupt != nil {
+		close(a.interrupt)
+		a.interrupt = nil
+	}
+	a.lock.Unlock()
+}
+
+// running returns the query being run and when it started.
+func (a *activity) running() (string, time.Time, bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.query, a.since, a.interrupt != nil
+}
@@ -0,0 +1,390 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sqltest
+
+import (
+	"context"
+	"database/sql/driver"
+	"math/rand"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Errors that can be injected using a Fault.
+var (
+	// ErrQueryInterrupted is returned by MySQL when a query is killed.
+	ErrQueryInterrupted error = errQueryInterrupted
+
+	// ErrLockDeadlock is returned by MySQL when a deadlock is detected.
+	ErrLockDeadlock error = &mysql.MySQLError{Number: 1213, SQLState: [5]byte{'4', '0', '0', '0', '1'}, Message: "Deadlock found when trying to get lock; try restarting transaction"}
+)
+
+// Fault describes a fault injected by a FaultConnector.
+type Fault struct {
+
+	// Query restricts the fault to statements matching the regular expression.
+	// Transactions are matched as BEGIN, COMMIT and ROLLBACK. If nil, every
+	// statement is matched.
+	Query *regexp.Regexp
+
+	// Rate is the probability (between 0 and 1) that the fault is injected into
+	// a matching statement. A value of zero never injects the fault (unless Always
+	// is set) and a value of 1 or more injects it every time.
+	Rate float64
+
+	// Always injects the fault into every matching statement, regardless of Rate.
+	Always bool
+
+	// Latency delays the statement before it is sent.
+	Latency time.Duration
+
+	// RowLatency delays each call to Next and Close on the returned rows.
+	// It can be used to make Rows.Close race cancelation.
+	RowLatency time.Duration
+
+	// Drop closes the connection as if the network dropped it.
+	// mysql.ErrInvalidConn is returned and the connection can no longer be used.
+	Drop bool
+
+	// Err is returned instead of sending the statement.
+	Err error
+}
+
+// FaultConnector is a driver.Connector that wraps another connector and
+// injects faults into the statements sent through it. It can be used to check
+// how an application behaves when a KILL signal fails, arrives after the query
+// has finished or races Rows.Close.
+//
+// Example:
+//
+//	// Killer pool whose KILL signals fail half the time
+//	fc := &sqltest.FaultConnector{
+//	   Connector: connector,
+//	   Faults: []sqltest.Fault{
+//	      {Query: regexp.MustCompile(`^KILL`), Rate: 0.5, Err: errors.New("killer pool down")},
+//	   },
+//	}
+//	pool.KillerPool = stdSql.OpenDB(fc)
+type FaultConnector struct {
+	Connector driver.Connector
+	Faults    []Fault
+
+	// Seed seeds the random number generator used to apply Rate.
+	// A value of zero uses the current time.
+	Seed int64
+
+	once sync.Once
+	lock sync.Mutex
+	rand *rand.Rand
+}
+
+// Connect implements the driver.Connector interface.
+func (fc *FaultConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := fc.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &faultConn{fc: fc, conn: conn}, nil
+}
+
+// Driver implements the driver.Connector interface.
+func (fc *FaultConnector) Driver() driver.Driver {
+	return fc.Connector.Driver()
+}
+
+// roll reports whether a fault with the given rate should be injected.
+func (fc *FaultConnector) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if rate >= 1 {
+		return true
+	}
+
+	fc.once.Do(func() {
+		seed := fc.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		fc.rand = rand.New(rand.NewSource(seed))
+	})
+
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	return fc.rand.Float64() < rate
+}
+
+// faultConn is a connection obtained from a FaultConnector.
+type faultConn struct {
+	fc   *FaultConnector
+	conn driver.Conn
+	bad  int32
+}
+
+// inject applies the faults matching query. It returns the latency to apply to rows.
+func (c *faultConn) inject(ctx context.Context, query string) (time.Duration, error) {
+
+	if atomic.LoadInt32(&c.bad) == 1 {
+		return 0, driver.ErrBadConn
+	}
+
+	var rowLatency time.Duration
+
+	for _, f := range c.fc.Faults {
+		if (f.Query != nil && !f.Query.MatchString(query)) || !(f.Always || c.fc.roll(f.Rate)) {
+			continue
+		}
+
+		if f.Latency > 0 {
+			timer := time.NewTimer(f.Latency)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return 0, ctx.Err()
+			}
+		}
+
+		if f.Drop {
+			atomic.StoreInt32(&c.bad, 1)
+			c.conn.Close()
+			return 0, mysql.ErrInvalidConn
+		}
+
+		if f.Err != nil {
+			return 0, f.Err
+		}
+
+		if f.RowLatency > rowLatency {
+			rowLatency = f.RowLatency
+		}
+	}
+
+	return rowLatency, nil
+}
+
+func (c *faultConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *faultConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if atomic.LoadInt32(&c.bad) == 1 {
+		return nil, driver.ErrBadConn
+	}
+
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if cp, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = cp.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &faultStmt{c: c, stmt: stmt, query: query}, nil
+}
+
+func (c *faultConn) Close() error {
+	if atomic.LoadInt32(&c.bad) == 1 {
+		return nil // Already closed
+	}
+	return c.conn.Close()
+}
+
+func (c *faultConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *faultConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.inject(ctx, "BEGIN"); err != nil {
+		return nil, err
+	}
+
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if cb, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err = cb.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &faultTx{c: c, tx: tx}, nil
+}
+
+func (c *faultConn) Ping(ctx context.Context) error {
+	if atomic.LoadInt32(&c.bad) == 1 {
+		return driver.ErrBadConn
+	}
+	if p, ok := c.conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *faultConn) ResetSession(ctx context.Context) error {
+	if atomic.LoadInt32(&c.bad) == 1 {
+		return driver.ErrBadConn
+	}
+	if sr, ok := c.conn.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *faultConn) IsValid() bool {
+	if atomic.LoadInt32(&c.bad) == 1 {
+		return false
+	}
+	if v, ok := c.conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *faultConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if _, err := c.inject(ctx, query); err != nil {
+		return nil, err
+	}
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *faultConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rowLatency, err := c.inject(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return wrapRows(rows, rowLatency), nil
+}
+
+func (c *faultConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type faultTx struct {
+	c  *faultConn
+	tx driver.Tx
+}
+
+func (tx *faultTx) Commit() error {
+	if _, err := tx.c.inject(context.Background(), "COMMIT"); err != nil {
+		return err
+	}
+	return tx.tx.Commit()
+}
+
+func (tx *faultTx) Rollback() error {
+	if _, err := tx.c.inject(context.Background(), "ROLLBACK"); err != nil {
+		return err
+	}
+	return tx.tx.Rollback()
+}
+
+type faultStmt struct {
+	c     *faultConn
+	stmt  driver.Stmt
+	query string
+}
+
+func (s *faultStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *faultStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *faultStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if _, err := s.c.inject(context.Background(), s.query); err != nil {
+		return nil, err
+	}
+	return s.stmt.Exec(args)
+}
+
+func (s *faultStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rowLatency, err := s.c.inject(context.Background(), s.query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.stmt.Query(args)
+	if err != nil {
+		return nil, err
+	}
+	return wrapRows(rows, rowLatency), nil
+}
+
+func (s *faultStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if _, err := s.c.inject(ctx, s.query); err != nil {
+		return nil, err
+	}
+	if se, ok := s.stmt.(driver.StmtExecContext); ok {
+		return se.ExecContext(ctx, args)
+	}
+	return s.stmt.Exec(values(args))
+}
+
+func (s *faultStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rowLatency, err := s.c.inject(ctx, s.query)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows driver.Rows
+	if sq, ok := s.stmt.(driver.StmtQueryContext); ok {
+		rows, err = sq.QueryContext(ctx, args)
+	} else {
+		rows, err = s.stmt.Query(values(args))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return wrapRows(rows, rowLatency), nil
+}
+
+// faultRows delays each call to Next and Close.
+type faultRows struct {
+	driver.Rows
+	latency time.Duration
+}
+
+func wrapRows(rows driver.Rows, latency time.Duration) driver.Rows {
+	if latency <= 0 {
+		return rows
+	}
+	return &faultRows{rows, latency}
+}
+
+func (rs *faultRows) Next(dest []driver.Value) error {
+	time.Sleep(rs.latency)
+	return rs.Rows.Next(dest)
+}
+
+func (rs *faultRows) Close() error {
+	time.Sleep(rs.latency)
+	return rs.Rows.Close()
+}
@@ -0,0 +1,122 @@
+package sqltest
+
+import (
+	"context"
+	stdSql "database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultConnector(t *testing.T) {
+	rec := NewRecorder()
+	fc := &FaultConnector{
+		Connector: rec,
+		Faults: []Fault{
+			{Query: regexp.MustCompile(`^UPDATE`), Always: true, Err: ErrLockDeadlock},
+			{Query: regexp.MustCompile(`^DELETE`), Always: true, Drop: true},
+			{Query: regexp.MustCompile(`^SELECT 1`), Err: errors.New("never injected")},
+		},
+	}
+
+	pool := &sql.DB{DB: stdSql.OpenDB(fc)}
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "UPDATE users SET name = 'bob'")
+	assert.Equal(t, ErrLockDeadlock, err)
+
+	// A Rate of zero never injects the fault
+	_, err = conn.ExecContext(ctx, "SELECT 1")
+	assert.NoError(t, err)
+
+	_, err = conn.ExecContext(ctx, "DELETE FROM users")
+	assert.Equal(t, mysql.ErrInvalidConn, err)
+
+	// The connection has been dropped
+	_, err = conn.ExecContext(ctx, "SELECT 1")
+	assert.Error(t, err)
+}
+
+func TestFaultConnectorRate(t *testing.T) {
+	errFault := errors.New("fault")
+
+	// outcomes runs 100 statements and reports which ones failed
+	outcomes := func(seed int64) []bool {
+		fc := &FaultConnector{
+			Connector: NewRecorder(),
+			Faults:    []Fault{{Query: regexp.MustCompile(`^SELECT 1$`), Rate: 0.5, Err: errFault}},
+			Seed:      seed,
+		}
+		pool := &sql.DB{DB: stdSql.OpenDB(fc)}
+		defer pool.Close()
+
+		conn, err := pool.Conn(context.Background())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var failed []bool
+		for i := 0; i < 100; i++ {
+			_, err := conn.ExecContext(context.Background(), "SELECT 1")
+			if err != nil {
+				require.Equal(t, errFault, err)
+			}
+			failed = append(failed, err != nil)
+		}
+		return failed
+	}
+
+	first := outcomes(42)
+
+	var n int
+	for _, failed := range first {
+		if failed {
+			n++
+		}
+	}
+	assert.True(t, n > 25 && n < 75, n)
+
+	// The same Seed injects the same faults
+	assert.Equal(t, first, outcomes(42))
+	assert.NotEqual(t, first, outcomes(43))
+}
+
+func TestFaultConnectorKillerPool(t *testing.T) {
+	rec := NewRecorder()
+	rec.Expect(`^UPDATE`).WillDelayFor(200 * time.Millisecond)
+
+	killer := &FaultConnector{
+		Connector: rec,
+		Faults: []Fault{
+			{Query: regexp.MustCompile(`^KILL`), Always: true, Err: errors.New("killer pool down")},
+		},
+	}
+
+	pool := &sql.DB{DB: stdSql.OpenDB(rec), KillerPool: stdSql.OpenDB(killer)}
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = conn.ExecContext(ctx, "UPDATE users SET name = 'bob'")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	stats := pool.CancelStats()
+	assert.Equal(t, uint64(1), stats.KillsAttempted)
+	assert.Equal(t, uint64(1), stats.KillsFailed)
+	assert.Empty(t, rec.Kills())
+}
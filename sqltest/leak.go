@@ -0,0 +1,55 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sqltest
+
+import (
+	"time"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+)
+
+// TestingT is the subset of testing.TB used by VerifyNoLeaks.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// LeakTimeout is how long VerifyNoLeaks waits for goroutines started by
+// ExecContext to exit (for example, after a KILL signal interrupts the query).
+var LeakTimeout = time.Second
+
+// VerifyNoLeaks fails the test if a goroutine started by Conn.ExecContext,
+// Tx.ExecContext or Stmt.ExecContext is still running, or if a Conn, Tx, Stmt or Rows
+// obtained from db was not closed or not unleaked.
+//
+// db.TrackHandles must be set before any handles are obtained.
+//
+// Example:
+//
+//	pool := rec.DB()
+//	pool.TrackHandles = true
+//	defer sqltest.VerifyNoLeaks(t, pool)
+func VerifyNoLeaks(t TestingT, db *sql.DB) {
+	t.Helper()
+
+	if !db.TrackHandles {
+		t.Errorf("sqltest: TrackHandles must be set to verify leaks")
+		return
+	}
+
+	deadline := time.Now().Add(LeakTimeout)
+	for {
+		leaks := db.Leaks()
+		if len(leaks) == 0 {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			for _, l := range leaks {
+				t.Errorf("sqltest: %s\n%s", l, l.Stack)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,177 @@
+package sqltest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestVerifyNoLeaks(t *testing.T) {
+	rec := NewRecorder()
+	rec.Expect(`^SELECT name`).WillReturnRows([]string{"name"}, []interface{}{"alice"})
+	rec.Expect(`^UPDATE`).WillDelayFor(10 * time.Second)
+
+	pool := rec.DB()
+	pool.TrackHandles = true
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+
+	// Rows closed automatically by Next
+	rows, err := conn.QueryContext(context.Background(), "SELECT name FROM users")
+	require.NoError(t, err)
+	for rows.Next() {
+	}
+	require.NoError(t, rows.Err())
+
+	// Killed exec
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = conn.ExecContext(ctx, "UPDATE users SET name = ?", "bob")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	tx, err := conn.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	_, err = tx.PrepareContext(context.Background(), "SELECT name FROM users")
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	require.NoError(t, conn.Close())
+
+	ft := &fakeT{}
+	VerifyNoLeaks(ft, pool)
+	assert.Empty(t, ft.errors)
+}
+
+func TestVerifyNoLeaksReportsLeaks(t *testing.T) {
+	rec := NewRecorder()
+
+	pool := rec.DB()
+	pool.TrackHandles = true
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stmt, err := conn.PrepareContext(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(context.Background())
+	require.NoError(t, err)
+	defer rows.Close()
+
+	ft := &fakeT{}
+	VerifyNoLeaks(ft, pool)
+	require.Len(t, ft.errors, 3)
+	assert.True(t, strings.HasPrefix(ft.errors[0], "sqltest: Conn not closed"), ft.errors[0])
+	assert.True(t, strings.HasPrefix(ft.errors[1], "sqltest: Stmt not closed"), ft.errors[1])
+	assert.True(t, strings.HasPrefix(ft.errors[2], "sqltest: Rows not closed"), ft.errors[2])
+}
+
+func TestVerifyNoLeaksConcurrentClose(t *testing.T) {
+	rec := NewRecorder()
+	rec.Expect(`^SELECT name`).WillReturnRows([]string{"name"}, []interface{}{"alice"})
+
+	pool := rec.DB()
+	pool.TrackHandles = true
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		rows, err := conn.QueryContext(context.Background(), "SELECT name FROM users")
+		require.NoError(t, err)
+
+		// Leaks can be called while handles are being released
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rows.Close()
+		}()
+		pool.Leaks()
+	}
+	wg.Wait()
+	require.NoError(t, conn.Close())
+
+	ft := &fakeT{}
+	VerifyNoLeaks(ft, pool)
+	assert.Empty(t, ft.errors)
+}
+
+func TestVerifyNoLeaksFinalizer(t *testing.T) {
+	var (
+		lock sync.Mutex
+		buf  bytes.Buffer
+	)
+	logged := func() string {
+		lock.Lock()
+		defer lock.Unlock()
+		return buf.String()
+	}
+
+	pool := NewRecorder().DB()
+	pool.TrackHandles = true
+	pool.Logger = sql.NewSlogLogger(slog.NewTextHandler(writerFunc(func(p []byte) (int, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return buf.Write(p)
+	}), nil))
+	defer pool.Close()
+
+	// The Conn is not closed since it is held by the leaked Rows
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+
+	func() {
+		_, err := conn.QueryContext(context.Background(), "SELECT 1")
+		require.NoError(t, err)
+	}()
+
+	// Tracking does not prevent the leaked Rows from being garbage collected
+	assert.Eventually(t, func() bool {
+		runtime.GC()
+		return strings.Contains(logged(), `msg="leaked Rows"`)
+	}, time.Second, 10*time.Millisecond)
+
+	ft := &fakeT{}
+	VerifyNoLeaks(ft, pool)
+	if assert.Len(t, ft.errors, 2) {
+		assert.True(t, strings.HasPrefix(ft.errors[1], "sqltest: Rows not closed"), ft.errors[1])
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestVerifyNoLeaksRequiresTracking(t *testing.T) {
+	ft := &fakeT{}
+	VerifyNoLeaks(ft, NewRecorder().DB())
+	assert.Equal(t, []string{"sqltest: TrackHandles must be set to verify leaks"}, ft.errors)
+}
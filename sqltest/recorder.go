@@ -0,0 +1,395 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sqltest
+
+import (
+	"context"
+	stdSql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	sql "github.com/rocketlaunchr/mysql-go"
+)
+
+// Statement is a statement recorded by a Recorder.
+type Statement struct {
+	ConnectionID string
+	Query        string
+	Args         []driver.Value
+}
+
+// Expectation is the response to statements matching a pattern.
+// It is created using Recorder.Expect.
+type Expectation struct {
+	pattern *regexp.Regexp
+
+	columns      []string
+	rows         [][]driver.Value
+	lastInsertID int64
+	rowsAffected int64
+	err          error
+	delay        time.Duration
+}
+
+// WillReturnRows sets the result set returned by the statement.
+func (e *Expectation) WillReturnRows(columns []string, rows ...[]interface{}) *Expectation {
+	e.columns = columns
+	e.rows = nil
+	for _, row := range rows {
+		vals := make([]driver.Value, len(row))
+		for i, v := range row {
+			val, err := driver.DefaultParameterConverter.ConvertValue(v)
+			if err != nil {
+				panic(fmt.Sprintf("sqltest: invalid value %v: %v", v, err))
+			}
+			vals[i] = val
+		}
+		e.rows = append(e.rows, vals)
+	}
+	return e
+}
+
+// WillReturnResult sets the result returned by the statement.
+func (e *Expectation) WillReturnResult(lastInsertID, rowsAffected int64) *Expectation {
+	e.lastInsertID, e.rowsAffected = lastInsertID, rowsAffected
+	return e
+}
+
+// WillReturnError sets the error returned by the statement.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// WillDelayFor delays the response to simulate a slow statement. If the statement
+// is killed while it is delayed, ER_QUERY_INTERRUPTED (1317) is returned.
+func (e *Expectation) WillDelayFor(d time.Duration) *Expectation {
+	e.delay = d
+	return e
+}
+
+// Recorder is a database/sql driver.Connector that records every statement
+// and responds using the expectations set using Expect. Unlike a mock of the SQLDB
+// interface, it produces real Conn, Tx, Stmt and Rows values, so that the
+// cancelation logic of the sql package is exercised.
+//
+// Each connection is given a connection id (returned by SELECT CONNECTION_ID()).
+// KILL statements are recorded and interrupt the statement running on the
+// connection.
+//
+// Example:
+//
+//	rec := sqltest.NewRecorder()
+//	rec.Expect(`^UPDATE`).WillDelayFor(10 * time.Second)
+//	pool := rec.DB()
+//
+//	conn, _ := pool.Conn(ctx)
+//	conn.ExecContext(ctx, "UPDATE ...") // ctx is canceled
+//
+//	if !rec.Killed(connectionID) { ... }
+type Recorder struct {
+	lock         sync.Mutex
+	expectations []*Expectation
+	statements   []Statement
+	kills        []string
+	conns        map[string]*recorderConn
+	nextID       uint64
+}
+
+// NewRecorder returns a new Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{conns: map[string]*recorderConn{}}
+}
+
+// DB returns a sql.DB whose pool and KillerPool both use the Recorder.
+func (r *Recorder) DB() *sql.DB {
+	return &sql.DB{DB: stdSql.OpenDB(r), KillerPool: stdSql.OpenDB(r)}
+}
+
+// Expect registers a response for statements matching the regular expression pattern.
+// Expectations are matched in the order they were registered and may be matched
+// any number of times. Statements that match no expectation return an empty result.
+// Expect panics if pattern can not be compiled.
+func (r *Recorder) Expect(pattern string) *Expectation {
+	e := &Expectation{pattern: regexp.MustCompile(pattern)}
+
+	r.lock.Lock()
+	r.expectations = append(r.expectations, e)
+	r.lock.Unlock()
+	return e
+}
+
+// Statements returns every statement recorded, in the order they were received.
+func (r *Recorder) Statements() []Statement {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Statement(nil), r.statements...)
+}
+
+// Kills returns the connection ids that KILL statements were received for.
+func (r *Recorder) Kills() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.kills...)
+}
+
+// Killed reports whether a KILL statement was received for the connection.
+func (r *Recorder) Killed(connectionID string) bool {
+	for _, id := range r.Kills() {
+		if id == connectionID {
+			return true
+		}
+	}
+	return false
+}
+
+// Connect implements the driver.Connector interface.
+func (r *Recorder) Connect(ctx context.Context) (driver.Conn, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.nextID++
+	c := &recorderConn{r: r, id: strconv.FormatUint(r.nextID, 10)}
+	r.conns[c.id] = c
+	return c, nil
+}
+
+// Driver implements the driver.Connector interface.
+func (r *Recorder) Driver() driver.Driver {
+	return recorderDriver{}
+}
+
+type recorderDriver struct{}
+
+func (recorderDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("sqltest: use Recorder.DB")
+}
+
+var recorderKillRegexp = regexp.MustCompile(`(?i)^KILL\s+(QUERY\s+|CONNECTION\s+)?(\?|'?\d+'?)\s*;?$`)
+
+// run records a statement and determines its response.
+func (r *Recorder) run(ctx context.Context, c *recorderConn, query string, args []driver.Value) (*Expectation, error) {
+
+	r.lock.Lock()
+	r.statements = append(r.statements, Statement{ConnectionID: c.id, Query: query, Args: args})
+	r.lock.Unlock()
+
+	q := stripComments(query)
+
+	if connectionIDRegexp.MatchString(q) {
+		return &Expectation{columns: []string{"CONNECTION_ID()"}, rows: [][]driver.Value{{c.id}}}, nil
+	}
+
+	if m := recorderKillRegexp.FindStringSubmatch(q); m != nil {
+		id := trimQuotes(m[2])
+		if id == "?" && len(args) > 0 {
+			if b, ok := args[0].([]byte); ok {
+				id = string(b)
+			} else {
+				id = fmt.Sprint(args[0])
+			}
+		}
+
+		r.lock.Lock()
+		r.kills = append(r.kills, id)
+		target := r.conns[id]
+		r.lock.Unlock()
+
+		if target == nil {
+			return nil, &mysql.MySQLError{Number: erNoSuchThread, Message: "Unknown thread id: " + id}
+		}
+		target.kill()
+		return &Expectation{}, nil
+	}
+
+	e := r.match(query)
+	if e == nil {
+		return &Expectation{}, nil
+	}
+
+	if e.delay > 0 {
+		killed, err := c.wait(ctx, query, e.delay)
+		if err != nil {
+			return nil, err
+		}
+		if killed {
+			return nil, errQueryInterrupted
+		}
+	}
+
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e, nil
+}
+
+// match returns the first expectation that matches query.
+func (r *Recorder) match(query string) *Expectation {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, e := range r.expectations {
+		if e.pattern.MatchString(query) {
+			return e
+		}
+	}
+	return nil
+}
+
+func trimQuotes(s string) string {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// recorderConn is a connection to a Recorder.
+type recorderConn struct {
+	activity
+	r  *Recorder
+	id string
+}
+
+func (c *recorderConn) Prepare(query string) (driver.Stmt, error) {
+	return &recorderStmt{c: c, query: query}, nil
+}
+
+func (c *recorderConn) Close() error {
+	c.r.lock.Lock()
+	delete(c.r.conns, c.id)
+	c.r.lock.Unlock()
+	return nil
+}
+
+func (c *recorderConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recorderConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.r.run(ctx, c, "BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return recorderTx{c}, nil
+}
+
+func (c *recorderConn) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (c *recorderConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.r.run(ctx, c, query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	return recorderResult{e.lastInsertID, e.rowsAffected}, nil
+}
+
+func (c *recorderConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.r.run(ctx, c, query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	return &recorderRows{columns: e.columns, rows: e.rows}, nil
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vals[i] = arg.Value
+	}
+	return vals
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+type recorderTx struct {
+	c *recorderConn
+}
+
+func (tx recorderTx) Commit() error {
+	_, err := tx.c.r.run(context.Background(), tx.c, "COMMIT", nil)
+	return err
+}
+
+func (tx recorderTx) Rollback() error {
+	_, err := tx.c.r.run(context.Background(), tx.c, "ROLLBACK", nil)
+	return err
+}
+
+type recorderStmt struct {
+	c     *recorderConn
+	query string
+}
+
+func (s *recorderStmt) Close() error {
+	return nil
+}
+
+func (s *recorderStmt) NumInput() int {
+	return -1
+}
+
+func (s *recorderStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *recorderStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *recorderStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.ExecContext(ctx, s.query, args)
+}
+
+func (s *recorderStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.QueryContext(ctx, s.query, args)
+}
+
+type recorderResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r recorderResult) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r recorderResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+type recorderRows struct {
+	columns []string
+	rows    [][]driver.Value
+	pos     int
+}
+
+func (rs *recorderRows) Columns() []string {
+	return rs.columns
+}
+
+func (rs *recorderRows) Close() error {
+	return nil
+}
+
+func (rs *recorderRows) Next(dest []driver.Value) error {
+	if rs.pos >= len(rs.rows) {
+		return io.EOF
+	}
+	copy(dest, rs.rows[rs.pos])
+	rs.pos++
+	return nil
+}
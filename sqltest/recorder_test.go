@@ -0,0 +1,138 @@
+package sqltest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder()
+	rec.Expect(`^SELECT name FROM users`).WillReturnRows([]string{"name"}, []interface{}{"alice"}, []interface{}{"bob"})
+	rec.Expect(`^INSERT`).WillReturnResult(7, 1)
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.SQLConn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, "SELECT name FROM users WHERE id > ?", 3)
+	require.NoError(t, err)
+
+	var names []string
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"alice", "bob"}, names)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	res, err := tx.ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", "carol")
+	require.NoError(t, err)
+	id, _ := res.LastInsertId()
+	assert.Equal(t, int64(7), id)
+	require.NoError(t, tx.Commit())
+
+	var queries []string
+	for _, st := range rec.Statements() {
+		queries = append(queries, st.Query)
+	}
+	assert.Equal(t, []string{
+		"SELECT CONNECTION_ID()",
+		"SELECT name FROM users WHERE id > ?",
+		"BEGIN",
+		"INSERT INTO users (name) VALUES (?)",
+		"COMMIT",
+	}, queries)
+	assert.Empty(t, rec.Kills())
+}
+
+func TestRecorderSQLConnPrepare(t *testing.T) {
+	rec := NewRecorder()
+	rec.Expect(`^SELECT name FROM users`).WillReturnRows([]string{"name"}, []interface{}{"alice"})
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn, err := pool.SQLConn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stmt, err := conn.PrepareContext(ctx, "SELECT name FROM users WHERE id = ?")
+	require.NoError(t, err)
+	defer stmt.Close()
+
+	var name string
+	require.NoError(t, stmt.QueryRowContext(ctx, 1).Scan(&name))
+	assert.Equal(t, "alice", name)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+}
+
+func TestRecorderKill(t *testing.T) {
+	rec := NewRecorder()
+	rec.Expect(`^UPDATE`).WillDelayFor(10 * time.Second)
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var connectionID string
+	require.NoError(t, conn.QueryRowContext(context.Background(), "SELECT CONNECTION_ID()").Scan(&connectionID))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = conn.ExecContext(ctx, "UPDATE users SET name = ?", "bob")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, rec.Killed(connectionID))
+}
+
+func TestRecorderCancelStats(t *testing.T) {
+	rec := NewRecorder()
+	rec.Expect(`^SELECT name FROM users`).WillReturnRows([]string{"name"}, []interface{}{"alice"}, []interface{}{"bob"})
+
+	pool := rec.DB()
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err := conn.QueryContext(ctx, "SELECT name FROM users")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+
+	// Each method that observes the canceled context would send a KILL signal
+	cancel()
+	rows.Columns()
+	rows.ColumnTypes()
+	rows.Err()
+	for rows.Next() {
+	}
+	rows.Close()
+
+	assert.Len(t, rec.Kills(), 1)
+
+	stats := pool.CancelStats()
+	assert.Equal(t, uint64(1), stats.QueriesStarted)
+	assert.Equal(t, uint64(1), stats.QueriesFinished)
+	assert.Equal(t, uint64(1), stats.KillsAttempted)
+	assert.Equal(t, uint64(1), stats.KillsSucceeded)
+	assert.Equal(t, uint64(1), stats.KillLatency.Count)
+}
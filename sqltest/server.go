@@ -0,0 +1,677 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sqltest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL error numbers used by Server.
+const (
+	erUnknownCom    = 1047
+	erNoSuchThread  = 1094
+	erUnsupportedPS = 1295
+)
+
+// Builtin queries handled by Server.
+var (
+	connectionIDRegexp = regexp.MustCompile(`(?i)^SELECT\s+CONNECTION_ID\(\)\s*;?$`)
+	killRegexp         = regexp.MustCompile(`(?i)^KILL\s+(QUERY\s+|CONNECTION\s+)?'?(\d+)'?\s*;?$`)
+	sleepRegexp        = regexp.MustCompile(`(?i)^SELECT\s+SLEEP\(\s*([0-9.]+)\s*\)\s*;?$`)
+	processInfoRegexp  = regexp.MustCompile(`(?i)^SELECT\s+INFO\s+FROM\s+information_schema\.PROCESSLIST\s+WHERE\s+ID\s*=\s*'?(\d+)'?\s*;?$`)
+	processListRegexp  = regexp.MustCompile(`(?i)^SHOW\s+(FULL\s+)?PROCESSLIST\s*;?$`)
+)
+
+// Response is the reply to a query received by a Server.
+type Response struct {
+
+	// Columns and Rows form a result set. If Columns is empty, an OK packet is sent.
+	// Values may be nil (NULL), integers, floats, strings, []byte or time.Time.
+	Columns []string
+	Rows    [][]interface{}
+
+	// ColumnTypes optionally sets the database type of each column, as reported
+	// by ColumnType's DatabaseTypeName. For example: "DECIMAL", "JSON", "BIT" or
+	// "UNSIGNED INT". Otherwise, or for an unknown name, the type is determined
+	// using the column's first non-NULL value.
+	ColumnTypes []string
+
+	RowsAffected uint64
+	LastInsertID uint64
+
+	// Delay postpones the response to simulate a slow query. If the query is
+	// killed while it is delayed, ER_QUERY_INTERRUPTED (1317) is sent instead.
+	Delay time.Duration
+
+	// Err is sent instead of the result.
+	Err *mysql.MySQLError
+}
+
+// Server is an in-process stand-in for a MySQL server. It speaks enough of the
+// MySQL wire protocol to be used with github.com/go-sql-driver/mysql, so that
+// cancelation can be tested without Docker.
+//
+// The following queries are handled:
+//
+//	SELECT CONNECTION_ID()
+//	SELECT SLEEP(n)
+//	KILL [QUERY | CONNECTION] id
+//	SHOW [FULL] PROCESSLIST
+//	SELECT INFO FROM information_schema.PROCESSLIST WHERE ID = id
+//
+// Other queries are answered using the responses registered with Handle, or
+// with an OK packet if no response matches. Leading comments are ignored.
+//
+// Prepared statements are not supported. The DSN returned by DSN sets
+// interpolateParams=true so that queries with arguments are sent as text.
+type Server struct {
+	listener net.Listener
+
+	lock      sync.Mutex
+	responses []response
+	conns     map[uint32]*serverConn
+	nextID    uint32
+	queries   []string
+	closed    bool
+
+	wg sync.WaitGroup
+}
+
+type response struct {
+	pattern *regexp.Regexp
+	resp    Response
+}
+
+// NewServer starts a Server listening on a random local port.
+// Close must be called to stop the server.
+func NewServer() (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{listener: l, conns: map[uint32]*serverConn{}}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address the server is listening on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// DSN returns a data source name for connecting to the server using
+// github.com/go-sql-driver/mysql.
+func (s *Server) DSN() string {
+	return fmt.Sprintf("root@tcp(%s)/test?interpolateParams=true", s.Addr())
+}
+
+// Handle registers a response for queries matching the regular expression pattern.
+// Responses are matched in the order they were registered.
+// Handle panics if pattern can not be compiled.
+func (s *Server) Handle(pattern string, resp Response) {
+	re := regexp.MustCompile(pattern)
+
+	s.lock.Lock()
+	s.responses = append(s.responses, response{re, resp})
+	s.lock.Unlock()
+}
+
+// Queries returns every query received, in the order they were received.
+func (s *Server) Queries() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+// Close stops the server and closes every connection.
+func (s *Server) Close() error {
+	s.lock.Lock()
+	s.closed = true
+	for _, c := range s.conns {
+		c.kill(true)
+	}
+	s.lock.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		nc, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			nc.Close()
+			return
+		}
+		s.nextID++
+		c := &serverConn{id: s.nextID, conn: nc, r: bufio.NewReader(nc), started: time.Now()}
+		s.conns[c.id] = c
+		s.lock.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(c)
+
+			c.conn.Close()
+			s.lock.Lock()
+			delete(s.conns, c.id)
+			s.lock.Unlock()
+		}()
+	}
+}
+
+// handle runs the connection phase and then responds to commands until the
+// connection is closed.
+func (s *Server) handle(c *serverConn) {
+
+	if err := c.writeHandshake(); err != nil {
+		return
+	}
+	if _, err := c.readPacket(); err != nil {
+		return
+	}
+	if err := c.writeOK(0, 0); err != nil {
+		return
+	}
+
+	for {
+		data, err := c.readPacket()
+		if err != nil || len(data) == 0 {
+			return
+		}
+
+		switch data[0] {
+		case 0x01: // COM_QUIT
+			return
+		case 0x02, 0x0e, 0x1f: // COM_INIT_DB, COM_PING, COM_RESET_CONNECTION
+			err = c.writeOK(0, 0)
+		case 0x03: // COM_QUERY
+			err = s.query(c, string(data[1:]))
+		case 0x16: // COM_STMT_PREPARE
+			err = c.writeErr(&mysql.MySQLError{Number: erUnsupportedPS, Message: "sqltest: prepared statements are not supported (use interpolateParams=true)"})
+		case 0x19: // COM_STMT_CLOSE has no response
+		default:
+			err = c.writeErr(&mysql.MySQLError{Number: erUnknownCom, SQLState: [5]byte{'0', '8', 'S', '0', '1'}, Message: "Unknown command"})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// query responds to a COM_QUERY.
+func (s *Server) query(c *serverConn, query string) error {
+
+	s.lock.Lock()
+	s.queries = append(s.queries, query)
+	s.lock.Unlock()
+
+	q := stripComments(query)
+
+	if connectionIDRegexp.MatchString(q) {
+		return c.writeResultSet([]string{"CONNECTION_ID()"}, nil, [][]interface{}{{c.id}})
+	}
+
+	if m := killRegexp.FindStringSubmatch(q); m != nil {
+		id, _ := strconv.ParseUint(m[2], 10, 32)
+
+		s.lock.Lock()
+		target := s.conns[uint32(id)]
+		s.lock.Unlock()
+
+		if target == nil {
+			return c.writeErr(&mysql.MySQLError{Number: erNoSuchThread, Message: "Unknown thread id: " + m[2]})
+		}
+		target.kill(!strings.EqualFold(strings.TrimSpace(m[1]), "QUERY"))
+		return c.writeOK(0, 0)
+	}
+
+	if m := sleepRegexp.FindStringSubmatch(q); m != nil {
+		secs, _ := strconv.ParseFloat(m[1], 64)
+
+		// SLEEP returns 1 when interrupted
+		result := 0
+		if killed, _ := c.wait(context.Background(), query, time.Duration(secs*float64(time.Second))); killed {
+			result = 1
+		}
+		return c.writeResultSet([]string{"SLEEP(" + m[1] + ")"}, nil, [][]interface{}{{result}})
+	}
+
+	if m := processInfoRegexp.FindStringSubmatch(q); m != nil {
+		id, _ := strconv.ParseUint(m[1], 10, 32)
+
+		var rows [][]interface{}
+		for _, p := range s.processList() {
+			if p.id == uint32(id) {
+				rows = append(rows, []interface{}{p.info})
+			}
+		}
+		return c.writeResultSet([]string{"INFO"}, nil, rows)
+	}
+
+	if processListRegexp.MatchString(q) {
+		var rows [][]interface{}
+		for _, p := range s.processList() {
+			command, state := "Sleep", ""
+			if p.info != nil {
+				command, state = "Query", "executing"
+			}
+			rows = append(rows, []interface{}{p.id, "root", p.host, "test", command, int64(p.time.Seconds()), state, p.info})
+		}
+		return c.writeResultSet([]string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info"}, nil, rows)
+	}
+
+	resp, ok := s.match(query)
+	if !ok {
+		return c.writeOK(0, 0)
+	}
+
+	if resp.Delay > 0 {
+		if killed, _ := c.wait(context.Background(), query, resp.Delay); killed {
+			return c.writeErr(errQueryInterrupted)
+		}
+	}
+
+	switch {
+	case resp.Err != nil:
+		return c.writeErr(resp.Err)
+	case len(resp.Columns) > 0:
+		return c.writeResultSet(resp.Columns, resp.ColumnTypes, resp.Rows)
+	default:
+		return c.writeOK(resp.RowsAffected, resp.LastInsertID)
+	}
+}
+
+// match returns the first registered response that matches query.
+func (s *Server) match(query string) (Response, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, r := range s.responses {
+		if r.pattern.MatchString(query) {
+			return r.resp, true
+		}
+	}
+	return Response{}, false
+}
+
+type process struct {
+	id   uint32
+	host string
+	time time.Duration
+	info interface{} // Running query or nil
+}
+
+// processList returns the state of every connection.
+func (s *Server) processList() []process {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var list []process
+	for id := uint32(1); id <= s.nextID; id++ {
+		c := s.conns[id]
+		if c == nil {
+			continue
+		}
+
+		p := process{id: id, host: c.conn.RemoteAddr().String(), time: time.Since(c.started)}
+		if query, since, ok := c.running(); ok {
+			p.info = query
+			p.time = time.Since(since)
+		}
+
+		list = append(list, p)
+	}
+	return list
+}
+
+// stripComments removes leading comments and whitespace from query.
+func stripComments(query string) string {
+	for {
+		query = strings.TrimSpace(query)
+		if !strings.HasPrefix(query, "/*") {
+			return query
+		}
+		end := strings.Index(query, "*/")
+		if end == -1 {
+			return query
+		}
+		query = query[end+2:]
+	}
+}
+
+// serverConn is a connection to a Server.
+type serverConn struct {
+	id      uint32
+	conn    net.Conn
+	r       *bufio.Reader
+	seq     byte
+	started time.Time
+
+	activity
+}
+
+// kill interrupts the running query. If connection is set, the connection is closed.
+func (c *serverConn) kill(connection bool) {
+	c.activity.kill()
+	if connection {
+		c.conn.Close()
+	}
+}
+
+// readPacket reads a packet and sets the sequence id of the reply.
+func (c *serverConn) readPacket() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, err
+	}
+
+	n := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+	c.seq = header[3] + 1
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *serverConn) writePacket(data []byte) error {
+	if len(data) >= 1<<24-1 {
+		return errors.New("sqltest: packet too large")
+	}
+
+	pkt := make([]byte, 4, 4+len(data))
+	pkt[0], pkt[1], pkt[2] = byte(len(data)), byte(len(data)>>8), byte(len(data)>>16)
+	pkt[3] = c.seq
+	c.seq++
+
+	_, err := c.conn.Write(append(pkt, data...))
+	return err
+}
+
+// Capability flags
+const (
+	clientLongPassword  = 1 << 0
+	clientLongFlag      = 1 << 2
+	clientConnectWithDB = 1 << 3
+	clientProtocol41    = 1 << 9
+	clientTransactions  = 1 << 13
+	clientSecureConn    = 1 << 15
+	clientMultiResults  = 1 << 17
+	clientPluginAuth    = 1 << 19
+)
+
+const statusAutocommit = 0x0002
+
+// Character sets
+const (
+	charsetUTF8MB4 = 45 // utf8mb4_general_ci
+	charsetBinary  = 63
+)
+
+func (c *serverConn) writeHandshake() error {
+	c.seq = 0
+
+	caps := uint32(clientLongPassword | clientLongFlag | clientConnectWithDB | clientProtocol41 |
+		clientTransactions | clientSecureConn | clientMultiResults | clientPluginAuth)
+
+	data := []byte{10} // Protocol version
+	data = append(data, "8.0.0-sqltest"...)
+	data = append(data, 0)
+	data = appendUint32(data, c.id)
+	data = append(data, "abcdefgh"...) // Auth plugin data (part 1)
+	data = append(data, 0)
+	data = appendUint16(data, uint16(caps))
+	data = append(data, charsetUTF8MB4)
+	data = appendUint16(data, statusAutocommit)
+	data = appendUint16(data, uint16(caps>>16))
+	data = append(data, 21)
+	data = append(data, make([]byte, 10)...)
+	data = append(data, "ijklmnopqrst"...) // Auth plugin data (part 2)
+	data = append(data, 0)
+	data = append(data, "mysql_native_password"...)
+	data = append(data, 0)
+
+	return c.writePacket(data)
+}
+
+func (c *serverConn) writeOK(rowsAffected, lastInsertID uint64) error {
+	data := []byte{0x00}
+	data = appendLengthEncodedInteger(data, rowsAffected)
+	data = appendLengthEncodedInteger(data, lastInsertID)
+	data = appendUint16(data, statusAutocommit)
+	data = appendUint16(data, 0) // Warnings
+	return c.writePacket(data)
+}
+
+func (c *serverConn) writeEOF() error {
+	data := []byte{0xfe}
+	data = appendUint16(data, 0) // Warnings
+	data = appendUint16(data, statusAutocommit)
+	return c.writePacket(data)
+}
+
+func (c *serverConn) writeErr(err *mysql.MySQLError) error {
+	state := err.SQLState
+	if state == [5]byte{} {
+		state = [5]byte{'H', 'Y', '0', '0', '0'}
+	}
+
+	data := []byte{0xff}
+	data = appendUint16(data, err.Number)
+	data = append(data, '#')
+	data = append(data, state[:]...)
+	data = append(data, err.Message...)
+	return c.writePacket(data)
+}
+
+// MySQL column types
+const (
+	typeTiny       = 0x01
+	typeShort      = 0x02
+	typeLong       = 0x03
+	typeFloat      = 0x04
+	typeDouble     = 0x05
+	typeTimestamp  = 0x07
+	typeLongLong   = 0x08
+	typeInt24      = 0x09
+	typeDate       = 0x0a
+	typeTime       = 0x0b
+	typeDatetime   = 0x0c
+	typeYear       = 0x0d
+	typeVarChar    = 0x0f
+	typeBit        = 0x10
+	typeJSON       = 0xf5
+	typeNewDecimal = 0xf6
+	typeEnum       = 0xf7
+	typeSet        = 0xf8
+	typeBlob       = 0xfc
+	typeVarString  = 0xfd
+	typeString     = 0xfe
+	typeGeometry   = 0xff
+)
+
+// flagUnsigned is the column flag set for UNSIGNED integer columns.
+const flagUnsigned = 0x20
+
+// databaseTypes maps the database type names reported by the driver to a
+// column type and character set.
+var databaseTypes = map[string]struct {
+	typ     byte
+	charset uint16
+}{
+	"TINYINT":   {typeTiny, charsetBinary},
+	"SMALLINT":  {typeShort, charsetBinary},
+	"MEDIUMINT": {typeInt24, charsetBinary},
+	"INT":       {typeLong, charsetBinary},
+	"BIGINT":    {typeLongLong, charsetBinary},
+	"YEAR":      {typeYear, charsetBinary},
+	"FLOAT":     {typeFloat, charsetBinary},
+	"DOUBLE":    {typeDouble, charsetBinary},
+	"DECIMAL":   {typeNewDecimal, charsetBinary},
+	"DATE":      {typeDate, charsetBinary},
+	"DATETIME":  {typeDatetime, charsetBinary},
+	"TIMESTAMP": {typeTimestamp, charsetBinary},
+	"TIME":      {typeTime, charsetBinary},
+	"BIT":       {typeBit, charsetBinary},
+	"JSON":      {typeJSON, charsetBinary},
+	"GEOMETRY":  {typeGeometry, charsetBinary},
+	"BLOB":      {typeBlob, charsetBinary},
+	"VARBINARY": {typeVarString, charsetBinary},
+	"BINARY":    {typeString, charsetBinary},
+	"TEXT":      {typeBlob, charsetUTF8MB4},
+	"VARCHAR":   {typeVarChar, charsetUTF8MB4},
+	"CHAR":      {typeString, charsetUTF8MB4},
+	"ENUM":      {typeEnum, charsetUTF8MB4},
+	"SET":       {typeSet, charsetUTF8MB4},
+}
+
+func (c *serverConn) writeResultSet(columns []string, types []string, rows [][]interface{}) error {
+
+	if err := c.writePacket(appendLengthEncodedInteger(nil, uint64(len(columns)))); err != nil {
+		return err
+	}
+
+	for i, name := range columns {
+		typ, charset := columnType(rows, i)
+
+		var flags uint16
+		if i < len(types) {
+			typeName := types[i]
+			if strings.HasPrefix(typeName, "UNSIGNED ") {
+				typeName = strings.TrimPrefix(typeName, "UNSIGNED ")
+				flags |= flagUnsigned
+			}
+			if dt, ok := databaseTypes[typeName]; ok {
+				typ, charset = dt.typ, dt.charset
+			}
+		}
+
+		data := appendLengthEncodedString(nil, "def") // Catalog
+		data = appendLengthEncodedString(data, "test")
+		data = appendLengthEncodedString(data, "")
+		data = appendLengthEncodedString(data, "")
+		data = appendLengthEncodedString(data, name)
+		data = appendLengthEncodedString(data, name)
+		data = append(data, 0x0c)
+		data = appendUint16(data, charset)
+		data = appendUint32(data, 1<<16) // Column length
+		data = append(data, typ)
+		data = appendUint16(data, flags)
+		data = append(data, 0)    // Decimals
+		data = append(data, 0, 0) // Filler
+		if err := c.writePacket(data); err != nil {
+			return err
+		}
+	}
+
+	if err := c.writeEOF(); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		var data []byte
+		for i := range columns {
+			if i >= len(row) || row[i] == nil {
+				data = append(data, 0xfb) // NULL
+				continue
+			}
+			data = appendLengthEncodedString(data, formatValue(row[i]))
+		}
+		if err := c.writePacket(data); err != nil {
+			return err
+		}
+	}
+
+	return c.writeEOF()
+}
+
+// columnType returns the type and character set of column i, determined using
+// its first non-NULL value.
+func columnType(rows [][]interface{}, i int) (byte, uint16) {
+	for _, row := range rows {
+		if i >= len(row) || row[i] == nil {
+			continue
+		}
+		switch row[i].(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return typeLongLong, charsetBinary
+		case float32, float64:
+			return typeDouble, charsetBinary
+		case time.Time:
+			return typeDatetime, charsetBinary
+		case []byte:
+			return typeBlob, charsetBinary
+		}
+		break
+	}
+	return typeVarString, charsetUTF8MB4
+}
+
+// formatValue formats v as it is sent using the text protocol.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999")
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	}
+	return fmt.Sprint(v)
+}
+
+func appendLengthEncodedInteger(b []byte, n uint64) []byte {
+	switch {
+	case n < 251:
+		return append(b, byte(n))
+	case n < 1<<16:
+		return append(b, 0xfc, byte(n), byte(n>>8))
+	case n < 1<<24:
+		return append(b, 0xfd, byte(n), byte(n>>8), byte(n>>16))
+	}
+	b = append(b, 0xfe)
+	b = appendUint32(b, uint32(n))
+	return appendUint32(b, uint32(n>>32))
+}
+
+func appendLengthEncodedString(b []byte, s string) []byte {
+	b = appendLengthEncodedInteger(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendUint16(b []byte, n uint16) []byte {
+	return append(b, byte(n), byte(n>>8))
+}
+
+func appendUint32(b []byte, n uint32) []byte {
+	return append(b, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+}
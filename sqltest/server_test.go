@@ -0,0 +1,164 @@
+package sqltest
+
+import (
+	"context"
+	stdSql "database/sql"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openServer(t *testing.T) (*Server, *sql.DB, func()) {
+	srv, err := NewServer()
+	require.NoError(t, err)
+
+	pool, err := sql.Open("mysql", srv.DSN())
+	require.NoError(t, err)
+
+	return srv, pool, func() {
+		pool.Close()
+		srv.Close()
+	}
+}
+
+func TestServerQuery(t *testing.T) {
+	srv, pool, closeFn := openServer(t)
+	defer closeFn()
+
+	srv.Handle(`^SELECT id, name FROM users`, Response{
+		Columns: []string{"id", "name"},
+		Rows:    [][]interface{}{{1, "alice"}, {2, nil}},
+	})
+	srv.Handle(`^UPDATE`, Response{RowsAffected: 3})
+	srv.Handle(`^DELETE`, Response{Err: &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}})
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, "SELECT id, name FROM users WHERE id > ?", 0)
+	require.NoError(t, err)
+
+	var ids []int
+	var names []*string
+	for rows.Next() {
+		var id int
+		var name *string
+		require.NoError(t, rows.Scan(&id, &name))
+		ids = append(ids, id)
+		names = append(names, name)
+	}
+	assert.NoError(t, rows.Err())
+	assert.Equal(t, []int{1, 2}, ids)
+	assert.Equal(t, "alice", *names[0])
+	assert.Nil(t, names[1])
+
+	res, err := conn.ExecContext(ctx, "UPDATE users SET name = ?", "bob")
+	require.NoError(t, err)
+	n, _ := res.RowsAffected()
+	assert.Equal(t, int64(3), n)
+
+	_, err = conn.ExecContext(ctx, "DELETE FROM users")
+	if assert.IsType(t, &mysql.MySQLError{}, err) {
+		assert.Equal(t, uint16(1213), err.(*mysql.MySQLError).Number)
+	}
+
+	assert.Contains(t, srv.Queries(), "SELECT id, name FROM users WHERE id > 0")
+}
+
+func TestServerCancel(t *testing.T) {
+	srv, pool, closeFn := openServer(t)
+	defer closeFn()
+	srv.Handle(`^UPDATE`, Response{Delay: 10 * time.Second})
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = conn.ExecContext(ctx, "UPDATE users SET name = 'bob'")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	var connectionID string
+	require.NoError(t, conn.QueryRowContext(context.Background(), "SELECT CONNECTION_ID()").Scan(&connectionID))
+	assert.Contains(t, srv.Queries(), "KILL QUERY '"+connectionID+"'")
+
+	// The connection is usable after the query was killed
+	var slept int
+	require.NoError(t, conn.QueryRowContext(context.Background(), "SELECT SLEEP(0.01)").Scan(&slept))
+	assert.Equal(t, 0, slept)
+}
+
+func TestServerVerifyKill(t *testing.T) {
+	srv, pool, closeFn := openServer(t)
+	defer closeFn()
+	srv.Handle(`name = 'alice'`, Response{Delay: 150 * time.Millisecond})
+	srv.Handle(`name = 'bob'`, Response{Delay: 500 * time.Millisecond})
+
+	// Delay the PROCESSLIST check so that the KILL signal arrives late
+	cfg, err := mysql.ParseDSN(srv.DSN())
+	require.NoError(t, err)
+	connector, err := mysql.NewConnector(cfg)
+	require.NoError(t, err)
+	pool.KillerPool = stdSql.OpenDB(&FaultConnector{
+		Connector: connector,
+		Faults:    []Fault{{Query: regexp.MustCompile(`PROCESSLIST`), Always: true, Latency: 300 * time.Millisecond}},
+	})
+
+	pool.Tags = map[string]string{"app": "api"}
+	pool.VerifyKill = true
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := conn.ExecContext(ctx, "UPDATE users SET name = 'alice'")
+		first <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// Another query with the same tags runs on the connection once the first
+	// query has finished, but before the PROCESSLIST check
+	_, err = conn.ExecContext(context.Background(), "UPDATE users SET name = 'bob'")
+	assert.NoError(t, err)
+	assert.NoError(t, <-first)
+
+	for _, q := range srv.Queries() {
+		assert.NotContains(t, q, "KILL")
+	}
+
+	// A KILL signal is sent if the query is still running
+	srv.Handle(`UPDATE accounts`, Response{Delay: 10 * time.Second})
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = conn.ExecContext(ctx, "UPDATE accounts SET balance = 0")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	var killed bool
+	for _, q := range srv.Queries() {
+		if strings.HasPrefix(q, "KILL QUERY") {
+			killed = true
+		}
+	}
+	assert.True(t, killed)
+}
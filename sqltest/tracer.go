@@ -0,0 +1,99 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+// Package sqltest provides utilities for testing code that uses the
+// github.com/rocketlaunchr/mysql-go package.
+package sqltest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+)
+
+// RecordedSpan is a span recorded by a TracerRecorder.
+type RecordedSpan struct {
+	Name       string
+	Parent     *RecordedSpan
+	Attributes map[string]interface{}
+	Errors     []error
+	Start      time.Time
+	End        time.Time // Zero if the span has not ended
+}
+
+// TracerRecorder is an in-memory sql.Tracer that records every span.
+// It is safe for concurrent use.
+type TracerRecorder struct {
+	lock  sync.Mutex
+	spans []*RecordedSpan
+}
+
+type spanKey struct{}
+
+// Start implements the sql.Tracer interface.
+func (r *TracerRecorder) Start(ctx context.Context, name string) (context.Context, sql.Span) {
+	rs := &RecordedSpan{
+		Name:       name,
+		Attributes: map[string]interface{}{},
+		Start:      time.Now(),
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok {
+		rs.Parent = parent.rs
+	}
+
+	r.lock.Lock()
+	r.spans = append(r.spans, rs)
+	r.lock.Unlock()
+
+	s := &span{r: r, rs: rs}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Spans returns a copy of every span recorded, in the order they were started.
+func (r *TracerRecorder) Spans() []RecordedSpan {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	out := make([]RecordedSpan, 0, len(r.spans))
+	for _, rs := range r.spans {
+		cp := *rs
+		cp.Attributes = make(map[string]interface{}, len(rs.Attributes))
+		for k, v := range rs.Attributes {
+			cp.Attributes[k] = v
+		}
+		cp.Errors = append([]error(nil), rs.Errors...)
+		out = append(out, cp)
+	}
+	return out
+}
+
+// Reset discards every span recorded.
+func (r *TracerRecorder) Reset() {
+	r.lock.Lock()
+	r.spans = nil
+	r.lock.Unlock()
+}
+
+type span struct {
+	r  *TracerRecorder
+	rs *RecordedSpan
+}
+
+func (s *span) SetAttribute(key string, value interface{}) {
+	s.r.lock.Lock()
+	s.rs.Attributes[key] = value
+	s.r.lock.Unlock()
+}
+
+func (s *span) RecordError(err error) {
+	s.r.lock.Lock()
+	s.rs.Errors = append(s.rs.Errors, err)
+	s.r.lock.Unlock()
+}
+
+func (s *span) End() {
+	s.r.lock.Lock()
+	s.rs.End = time.Now()
+	s.r.lock.Unlock()
+}
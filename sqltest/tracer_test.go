@@ -0,0 +1,36 @@
+package sqltest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracerRecorder(t *testing.T) {
+	r := &TracerRecorder{}
+
+	ctx, parent := r.Start(context.Background(), "mysql Exec")
+	parent.SetAttribute("db.mysql.connection_id", "7")
+
+	_, child := r.Start(ctx, "mysql KILL QUERY")
+	child.RecordError(errors.New("kill failed"))
+	child.End()
+	parent.End()
+
+	spans := r.Spans()
+	assert.Len(t, spans, 2)
+
+	assert.Equal(t, "mysql Exec", spans[0].Name)
+	assert.Nil(t, spans[0].Parent)
+	assert.Equal(t, "7", spans[0].Attributes["db.mysql.connection_id"])
+	assert.False(t, spans[0].End.IsZero())
+
+	assert.Equal(t, "mysql KILL QUERY", spans[1].Name)
+	assert.Equal(t, "mysql Exec", spans[1].Parent.Name)
+	assert.Len(t, spans[1].Errors, 1)
+
+	r.Reset()
+	assert.Len(t, r.Spans(), 0)
+}
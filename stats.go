@@ -0,0 +1,158 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	stdSql "database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// killLatencyBuckets are the upper bounds of the kill latency histogram.
+var killLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// CancelStats contains statistics about queries and the KILL signals sent
+// when their contexts are canceled (or when a transaction exceeds
+// TxOptions.MaxDuration).
+type CancelStats struct {
+	QueriesStarted   uint64 // Exec, Query and QueryRow operations started on a Conn, Tx or Stmt.
+	QueriesFinished  uint64 // Exec, Query and QueryRow operations finished.
+	ContextsCanceled uint64 // Operations that finished with a canceled context.
+
+	// KillsAttempted is the sum of KillsSucceeded, KillsFailed and KillsTimedOut.
+	KillsAttempted uint64
+	KillsSucceeded uint64
+	KillsFailed    uint64
+	KillsTimedOut  uint64 // KILL signals that were not sent within KillTimeout.
+
+	// KillsSkipped is the number of KILL signals not sent because the connection
+	// was no longer running the query (see VerifyKill).
+	KillsSkipped uint64
+
+	// KillLatency is how long it took to send KILL signals.
+	KillLatency Histogram
+
+	// KillerPool contains the statistics of the KillerPool.
+	KillerPool stdSql.DBStats
+}
+
+// Histogram is a distribution of durations.
+type Histogram struct {
+	Buckets []time.Duration // Upper bounds of each bucket.
+	Counts  []uint64        // Counts[i] is the number of observations <= Buckets[i].
+	Count   uint64          // Total number of observations.
+	Sum     time.Duration   // Sum of all observations.
+}
+
+// cancelStats stores the counters reported by CancelStats.
+type cancelStats struct {
+	queriesStarted   uint64
+	queriesFinished  uint64
+	contextsCanceled uint64
+	killsSucceeded   uint64
+	killsFailed      uint64
+	killsTimedOut    uint64
+	killsSkipped     uint64
+	killLatencySum   int64
+	killLatency      []uint64 // Non-cumulative count of each bucket (last element is +Inf)
+}
+
+// CancelStats returns statistics about queries and the KILL signals sent.
+func (db *DB) CancelStats() CancelStats {
+	cs := db.cancelStats()
+
+	stats := CancelStats{
+		QueriesStarted:   atomic.LoadUint64(&cs.queriesStarted),
+		QueriesFinished:  atomic.LoadUint64(&cs.queriesFinished),
+		ContextsCanceled: atomic.LoadUint64(&cs.contextsCanceled),
+		KillsSucceeded:   atomic.LoadUint64(&cs.killsSucceeded),
+		KillsFailed:      atomic.LoadUint64(&cs.killsFailed),
+		KillsTimedOut:    atomic.LoadUint64(&cs.killsTimedOut),
+		KillsSkipped:     atomic.LoadUint64(&cs.killsSkipped),
+		KillLatency: Histogram{
+			Buckets: append([]time.Duration(nil), killLatencyBuckets...),
+			Counts:  make([]uint64, len(killLatencyBuckets)),
+			Sum:     time.Duration(atomic.LoadInt64(&cs.killLatencySum)),
+		},
+	}
+	stats.KillsAttempted = stats.KillsSucceeded + stats.KillsFailed + stats.KillsTimedOut
+
+	var cumulative uint64
+	for i := range cs.killLatency {
+		cumulative += atomic.LoadUint64(&cs.killLatency[i])
+		if i < len(killLatencyBuckets) {
+			stats.KillLatency.Counts[i] = cumulative
+		}
+	}
+	stats.KillLatency.Count = cumulative
+
+	if db.KillerPool != nil {
+		stats.KillerPool = db.KillerPool.Stats()
+	}
+
+	return stats
+}
+
+// cancelStats returns the counters of the DB, creating them if necessary.
+func (db *DB) cancelStats() *cancelStats {
+	db.statsOnce.Do(func() {
+		db.stats = &cancelStats{killLatency: make([]uint64, len(killLatencyBuckets)+1)}
+	})
+	return db.stats
+}
+
+// recordQuery records that a query was started (and must later be finished).
+func (cs *cancelStats) recordQuery(op Op) bool {
+	if op != OpExec && op != OpQuery && op != OpQueryRow {
+		return false
+	}
+	atomic.AddUint64(&cs.queriesStarted, 1)
+	return true
+}
+
+// recordFinished records that a query has finished.
+func (cs *cancelStats) recordFinished(ctx context.Context) {
+	atomic.AddUint64(&cs.queriesFinished, 1)
+	if ctx.Err() != nil {
+		atomic.AddUint64(&cs.contextsCanceled, 1)
+	}
+}
+
+// recordKill records the outcome of a KILL signal.
+func (cs *cancelStats) recordKill(latency time.Duration, err error) {
+	switch {
+	case err == nil:
+		atomic.AddUint64(&cs.killsSucceeded, 1)
+	case errors.Is(err, context.DeadlineExceeded):
+		atomic.AddUint64(&cs.killsTimedOut, 1)
+	default:
+		atomic.AddUint64(&cs.killsFailed, 1)
+	}
+
+	atomic.AddInt64(&cs.killLatencySum, int64(latency))
+
+	i := 0
+	for i < len(killLatencyBuckets) && latency > killLatencyBuckets[i] {
+		i++
+	}
+	atomic.AddUint64(&cs.killLatency[i], 1)
+}
+
+// recordKillSkipped records that a KILL signal was not sent.
+func (cs *cancelStats) recordKillSkipped() {
+	atomic.AddUint64(&cs.killsSkipped, 1)
+}
@@ -0,0 +1,39 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCancelStats(t *testing.T) {
+	db := &DB{}
+
+	cs := db.cancelStats()
+	cs.recordKill(2*time.Millisecond, nil)
+	cs.recordKill(20*time.Millisecond, errors.New("failed"))
+	cs.recordKill(10*time.Second, context.DeadlineExceeded)
+	cs.recordKill(10*time.Second, fmt.Errorf("kill: %w", context.DeadlineExceeded))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	db.intercept(ctx, &Call{Op: OpQuery}, func(ctx context.Context, call *Call) error { return nil })
+	db.intercept(context.Background(), &Call{Op: OpBegin}, func(ctx context.Context, call *Call) error { return nil })
+
+	stats := db.CancelStats()
+	assert.Equal(t, uint64(1), stats.QueriesStarted)
+	assert.Equal(t, uint64(1), stats.QueriesFinished)
+	assert.Equal(t, uint64(1), stats.ContextsCanceled)
+	assert.Equal(t, uint64(4), stats.KillsAttempted)
+	assert.Equal(t, uint64(1), stats.KillsSucceeded)
+	assert.Equal(t, uint64(1), stats.KillsFailed)
+	assert.Equal(t, uint64(2), stats.KillsTimedOut)
+
+	assert.Equal(t, uint64(4), stats.KillLatency.Count)
+	assert.Equal(t, 20*time.Second+22*time.Millisecond, stats.KillLatency.Sum)
+	assert.Equal(t, []uint64{0, 1, 1, 2, 2, 2, 2, 2, 2, 2, 2}, stats.KillLatency.Counts)
+}
@@ -5,16 +5,21 @@ package sql
 import (
 	"context"
 	stdSql "database/sql"
-	"time"
+	"sync/atomic"
 )
 
 // Stmt is a prepared statement.
 // A Stmt is safe for concurrent use by multiple goroutines.
 type Stmt struct {
-	stmt         *stdSql.Stmt
-	killerPool   StdSQLDB
-	connectionID string
-	kto          time.Duration
+	session
+	stmt  *stdSql.Stmt
+	query string // Query the statement was prepared with (if known)
+
+	// Record of the transaction kept by the TxMonitor (if prepared for a transaction)
+	monitored *txRecord
+
+	closed  int32
+	tracked *trackedHandle
 }
 
 // Unleak will release the reference to the killerPool
@@ -23,10 +28,13 @@ func (s *Stmt) Unleak() {
 	s.killerPool = nil
 	s.connectionID = ""
 	s.kto = 0
+	s.tracked.markUnleaked()
 }
 
 // Close closes the statement.
 func (s *Stmt) Close() error {
+	atomic.StoreInt32(&s.closed, 1)
+	s.tracked.markClosed()
 	err := s.stmt.Close()
 	if err != nil {
 		return err
@@ -44,44 +52,10 @@ func (s *Stmt) Exec(args ...interface{}) (stdSql.Result, error) {
 // ExecContext executes a prepared statement with the given arguments and
 // returns a Result summarizing the effect of the statement.
 func (s *Stmt) ExecContext(ctx context.Context, args ...interface{}) (stdSql.Result, error) {
-
-	// Create a context that is used to cancel ExecContext()
-	cancelCtx, cancelFunc := context.WithCancel(context.Background())
-	defer cancelFunc()
-
-	outChan := make(chan stdSql.Result)
-	errChan := make(chan error)
-	returnedChan := make(chan struct{}) // Used to indicate that this function has returned
-
-	defer func() {
-		returnedChan <- struct{}{}
-	}()
-
-	go func() {
-		select {
-		case <-ctx.Done():
-			// context has been canceled
-			kill(s.killerPool, s.connectionID, s.kto)
-			errChan <- ctx.Err()
-		case <-returnedChan:
-		}
-	}()
-
-	go func() {
-		res, err := s.stmt.ExecContext(cancelCtx, args...)
-		if err != nil {
-			errChan <- err
-			return
-		}
-		outChan <- res
-	}()
-
-	select {
-	case err := <-errChan:
-		return nil, err
-	case out := <-outChan:
-		return out, nil
-	}
+	s.statement()
+	return s.runExec(ctx, &Call{Query: s.query, Args: args, Stmt: true}, func(ctx context.Context, call *Call) (stdSql.Result, error) {
+		return s.stmt.ExecContext(ctx, call.Args...)
+	})
 }
 
 // Query executes a prepared query statement with the given arguments
@@ -93,17 +67,10 @@ func (s *Stmt) Query(args ...interface{}) (*Rows, error) {
 // QueryContext executes a prepared query statement with the given arguments
 // and returns the query results as a *Rows.
 func (s *Stmt) QueryContext(ctx context.Context, args ...interface{}) (*Rows, error) {
-
-	// We can't use the same approach used in ExecContext because defer cancelFunc()
-	// cancels rows.Scan.
-	defer func() {
-		if ctx.Err() != nil {
-			kill(s.killerPool, s.connectionID, s.kto)
-		}
-	}()
-
-	rows, err := s.stmt.QueryContext(ctx, args...)
-	return &Rows{ctx: ctx, rows: rows, killerPool: s.killerPool, connectionID: s.connectionID}, err
+	s.statement()
+	return s.runQuery(ctx, &Call{Query: s.query, Args: args, Stmt: true}, func(ctx context.Context, call *Call) (*stdSql.Rows, error) {
+		return s.stmt.QueryContext(ctx, call.Args...)
+	})
 }
 
 // QueryRow executes a prepared query statement with the given arguments.
@@ -128,14 +95,16 @@ func (s *Stmt) QueryRow(args ...interface{}) *Row {
 // Otherwise, the *Row's Scan scans the first selected row and discards
 // the rest.
 func (s *Stmt) QueryRowContext(ctx context.Context, args ...interface{}) *Row {
+	s.statement()
+	return s.runQueryRow(ctx, &Call{Query: s.query, Args: args, Stmt: true}, func(ctx context.Context, call *Call) (*stdSql.Rows, error) {
+		return s.stmt.QueryContext(ctx, call.Args...)
+	})
+}
 
-	// Since sql.Row does not export err field, this is the best we can do:
-	defer func() {
-		if ctx.Err() != nil {
-			kill(s.killerPool, s.connectionID, s.kto)
-		}
-	}()
-
-	row := s.stmt.QueryRowContext(ctx, args...)
-	return &Row{ctx: ctx, row: row, killerPool: s.killerPool, connectionID: s.connectionID}
+// statement records the statement as the last statement run by the transaction
+// it was prepared for.
+func (s *Stmt) statement() {
+	if s.monitored != nil && s.query != "" {
+		s.monitored.statement(s.query)
+	}
 }
@@ -0,0 +1,143 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	stdSql "database/sql"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type tagsKey struct{}
+
+// queryIDTag is the tag used to identify a query when VerifyKill is set.
+const queryIDTag = "query_id"
+
+// WithTags returns a copy of ctx with tags added to any tags already
+// stored in ctx. The tags are prepended as an SQL comment (in the sqlcommenter
+// format) to queries run on a Conn, Tx or Stmt using the returned context.
+//
+// The comment allows SHOW PROCESSLIST, performance_schema digests and the
+// server's slow query log to attribute load to callers.
+//
+// Example:
+//
+//	ctx = sql.WithTags(ctx, map[string]string{"route": "/users/:id"})
+//	// Runs: /* app='api',route='%2Fusers%2F%3Aid' */ SELECT ...
+func WithTags(ctx context.Context, tags map[string]string) context.Context {
+	existing, _ := ctx.Value(tagsKey{}).(map[string]string)
+
+	merged := make(map[string]string, len(existing)+len(tags))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, tagsKey{}, merged)
+}
+
+// comment returns the SQL comment built from db.Tags and the tags stored in ctx.
+// If VerifyKill is set, a unique query_id tag is added. It returns an empty string
+// if there are no tags.
+func (db *DB) comment(ctx context.Context) string {
+	if db == nil {
+		return ""
+	}
+
+	ctxTags, _ := ctx.Value(tagsKey{}).(map[string]string)
+	if len(db.Tags) == 0 && len(ctxTags) == 0 && !db.VerifyKill {
+		return ""
+	}
+
+	tags := make(map[string]string, len(db.Tags)+len(ctxTags)+1)
+	for k, v := range db.Tags {
+		tags[k] = v
+	}
+	for k, v := range ctxTags {
+		tags[k] = v
+	}
+	if db.VerifyKill {
+		// Identifies the query in information_schema.PROCESSLIST (see stillRunning)
+		tags[queryIDTag] = strconv.FormatUint(atomic.AddUint64(&db.queryIDs, 1), 10)
+	}
+	return formatComment(tags)
+}
+
+// formatComment formats tags as an SQL comment with the keys sorted.
+// Keys and values are URL encoded so that the comment can not be terminated early.
+func formatComment(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("/* ")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(escapeTag(k))
+		b.WriteString("='")
+		b.WriteString(escapeTag(tags[k]))
+		b.WriteByte('\'')
+	}
+	b.WriteString(" */")
+	return b.String()
+}
+
+func escapeTag(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+// tagQuery prepends the comment to query.
+func tagQuery(comment, query string) string {
+	if comment == "" {
+		return query
+	}
+	return comment + " " + query
+}
+
+// stillRunning reports whether the connection is still running a query tagged
+// with comment, by checking information_schema.PROCESSLIST. The query is matched
+// on its query_id tag (anywhere in the query) since an Interceptor may have
+// rewritten it. If the check fails, it reports true so that the KILL signal is still sent.
+func stillRunning(killerPool StdSQLDB, connectionID string, comment string, kto time.Duration) bool {
+
+	ctx := context.Background()
+	if kto != 0 {
+		var cancelFunc context.CancelFunc
+		ctx, cancelFunc = context.WithTimeout(ctx, kto)
+		defer cancelFunc()
+	}
+
+	var info stdSql.NullString
+	err := killerPool.QueryRowContext(ctx, "SELECT INFO FROM information_schema.PROCESSLIST WHERE ID = ?", connectionID).Scan(&info)
+	if err == stdSql.ErrNoRows {
+		return false
+	} else if err != nil {
+		return true
+	}
+	return strings.Contains(info.String, queryIDOf(comment))
+}
+
+// queryIDOf returns the query_id tag (such as query_id='1') found in comment,
+// or the whole comment if it has none.
+func queryIDOf(comment string) string {
+	i := strings.Index(comment, queryIDTag+"='")
+	if i < 0 {
+		return comment
+	}
+	j := strings.IndexByte(comment[i+len(queryIDTag)+2:], '\'')
+	if j < 0 {
+		return comment
+	}
+	return comment[i : i+len(queryIDTag)+2+j+1]
+}
@@ -0,0 +1,46 @@
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComment(t *testing.T) {
+	db := &DB{}
+	assert.Equal(t, "", db.comment(context.Background()))
+
+	db.Tags = map[string]string{"app": "api", "route": "default"}
+
+	ctx := WithTags(context.Background(), map[string]string{"route": "/users/:id"})
+	ctx = WithTags(ctx, map[string]string{"trace_id": "abc 123"})
+	assert.Equal(t, "/* app='api',route='%2Fusers%2F%3Aid',trace_id='abc%20123' */", db.comment(ctx))
+
+	// The comment can not be terminated early
+	ctx = WithTags(context.Background(), map[string]string{"x": "*/ DROP TABLE t; /*'"})
+	assert.Equal(t, "/* app='api',route='default',x='%2A%2F%20DROP%20TABLE%20t%3B%20%2F%2A%27' */", db.comment(ctx))
+
+	// Every query is given a unique query_id if VerifyKill is set
+	db = &DB{VerifyKill: true}
+	assert.Equal(t, "/* query_id='1' */", db.comment(context.Background()))
+	assert.Equal(t, "/* query_id='2' */", db.comment(context.Background()))
+}
+
+func TestQueryIDOf(t *testing.T) {
+	assert.Equal(t, "query_id='12'", queryIDOf("/* app='api',query_id='12',route='%2F' */"))
+	assert.Equal(t, "/* app='api' */", queryIDOf("/* app='api' */"))
+}
+
+func TestTag(t *testing.T) {
+	s := &session{db: &DB{Tags: map[string]string{"app": "api"}}, comment: "/* prepared */"}
+
+	call := &Call{Query: "SELECT 1"}
+	assert.Equal(t, "/* app='api' */", s.tag(context.Background(), call))
+	assert.Equal(t, "/* app='api' */ SELECT 1", call.Query)
+
+	// Prepared statements are tagged when they are prepared
+	call = &Call{Query: "SELECT ?", Stmt: true}
+	assert.Equal(t, "/* prepared */", s.tag(context.Background(), call))
+	assert.Equal(t, "SELECT ?", call.Query)
+}
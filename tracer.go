@@ -0,0 +1,74 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+)
+
+// Tracer creates spans for the operations run on a Conn, Tx or Stmt and for
+// the KILL signals sent when a context is canceled.
+//
+// It is a minimal interface so that this package does not depend on a tracing SDK.
+// See the otel subpackage for an OpenTelemetry adapter.
+type Tracer interface {
+	// Start creates a span that is a child of any span contained in ctx.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span represents an operation being traced.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Span attributes.
+const (
+	attrSystem       = "db.system"
+	attrOperation    = "db.operation"
+	attrStatement    = "db.statement"
+	attrRowsAffected = "db.rows_affected"
+	attrConnectionID = "db.mysql.connection_id"
+	attrKilled       = "db.mysql.killed"
+	attrKillLatency  = "db.mysql.kill_latency_ms"
+)
+
+type spanKey struct{}
+
+// trace creates a span for an operation. The query is sanitized before being recorded.
+// The span of a Query or QueryRow ends once its rows are closed.
+func (db *DB) trace(ctx context.Context, call *Call, next Handler) error {
+
+	ctx, span := db.Tracer.Start(ctx, "mysql "+call.Op.String())
+	ctx = context.WithValue(ctx, spanKey{}, span)
+
+	span.SetAttribute(attrSystem, "mysql")
+	span.SetAttribute(attrOperation, call.Op.String())
+	span.SetAttribute(attrConnectionID, call.ConnectionID)
+	if call.Query != "" {
+		span.SetAttribute(attrStatement, SanitizeQuery(call.Query))
+	}
+
+	err := next(ctx, call)
+
+	if call.Result != nil {
+		if n, rErr := call.Result.RowsAffected(); rErr == nil {
+			span.SetAttribute(attrRowsAffected, n)
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+	} else if call.Op == OpQuery || call.Op == OpQueryRow {
+		// The query runs until the rows have been read
+		call.OnClose(func() {
+			if call.Killed {
+				span.SetAttribute(attrKilled, true)
+			}
+			span.End()
+		})
+		return nil
+	}
+	span.End()
+	return err
+}
@@ -0,0 +1,116 @@
+package sql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+// spanNames returns the names of the spans recorded that are not for CONNECTION_ID.
+func spanNames(tracer *sqltest.TracerRecorder) []string {
+	var names []string
+	for _, s := range tracer.Spans() {
+		if s.Attributes["db.statement"] == "SELECT CONNECTION_ID()" {
+			continue
+		}
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestTracerSpans(t *testing.T) {
+	ctx := context.Background()
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^SELECT name`).WillReturnRows([]string{"name"}, []interface{}{"alice"})
+	rec.Expect(`^UPDATE`).WillReturnResult(0, 3)
+
+	tracer := &sqltest.TracerRecorder{}
+	pool := rec.DB()
+	pool.Tracer = tracer
+	defer pool.Close()
+
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	t.Run("Conn", func(t *testing.T) {
+		tracer.Reset()
+
+		res, err := conn.ExecContext(ctx, "UPDATE users SET active = 1 WHERE id = 5")
+		require.NoError(t, err)
+		n, _ := res.RowsAffected()
+		assert.Equal(t, int64(3), n)
+
+		spans := tracer.Spans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "mysql Exec", spans[0].Name)
+		assert.Equal(t, "mysql", spans[0].Attributes["db.system"])
+		assert.Equal(t, "UPDATE users SET active = ? WHERE id = ?", spans[0].Attributes["db.statement"])
+		assert.Equal(t, int64(3), spans[0].Attributes["db.rows_affected"])
+		assert.NotEmpty(t, spans[0].Attributes["db.mysql.connection_id"])
+		assert.False(t, spans[0].End.IsZero())
+	})
+
+	t.Run("Query ends at Close", func(t *testing.T) {
+		tracer.Reset()
+
+		rows, err := conn.QueryContext(ctx, "SELECT name FROM users")
+		require.NoError(t, err)
+
+		spans := tracer.Spans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "mysql Query", spans[0].Name)
+		assert.True(t, spans[0].End.IsZero())
+
+		require.NoError(t, rows.Close())
+		assert.False(t, tracer.Spans()[0].End.IsZero())
+	})
+
+	t.Run("QueryRow ends at Scan", func(t *testing.T) {
+		tracer.Reset()
+
+		var name string
+		require.NoError(t, conn.QueryRowContext(ctx, "SELECT name FROM users").Scan(&name))
+
+		spans := tracer.Spans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "mysql QueryRow", spans[0].Name)
+		assert.False(t, spans[0].End.IsZero())
+	})
+
+	t.Run("Tx", func(t *testing.T) {
+		tracer.Reset()
+
+		tx, err := conn.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		_, err = tx.ExecContext(ctx, "UPDATE users SET active = 1")
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+
+		assert.Equal(t, []string{"mysql Begin", "mysql Exec", "mysql Commit"}, spanNames(tracer))
+		for _, s := range tracer.Spans() {
+			assert.False(t, s.End.IsZero(), s.Name)
+		}
+	})
+
+	t.Run("Stmt", func(t *testing.T) {
+		tracer.Reset()
+
+		stmt, err := conn.PrepareContext(ctx, "SELECT name FROM users WHERE id = ?")
+		require.NoError(t, err)
+		defer stmt.Close()
+
+		rows, err := stmt.QueryContext(ctx, 5)
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+
+		assert.Equal(t, []string{"mysql Prepare", "mysql Query"}, spanNames(tracer))
+		for _, s := range tracer.Spans() {
+			assert.False(t, s.End.IsZero(), s.Name)
+		}
+	})
+}
@@ -5,7 +5,10 @@ package sql
 import (
 	"context"
 	stdSql "database/sql"
+	"errors"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,14 +23,33 @@ import (
 // the transaction's Prepare or Stmt methods are closed
 // by the call to Commit or Rollback.
 type Tx struct {
-	tx           *stdSql.Tx
-	killerPool   StdSQLDB
-	connectionID string
-	kto          time.Duration
+	session
+	tx *stdSql.Tx
 
 	// Lock and store stmts
 	lock  sync.Mutex
 	stmts []*Stmt
+
+	// Used to name savepoints created by BeginNested
+	savepoints uint32
+
+	// Callbacks registered using OnCommit, OnRollback and OnUnknown (guarded by lock)
+	commitHooks   []func()
+	rollbackHooks []func(err error)
+	unknownHooks  []func(err error)
+
+	// Outcome of the COMMIT issued by CommitContext (guarded by lock)
+	commit *commitState
+
+	// Record of the transaction kept by the TxMonitor (if any)
+	monitored *txRecord
+
+	// Used to enforce TxOptions.MaxDuration
+	timer *time.Timer
+	state int32
+
+	closed  int32
+	tracked *trackedHandle
 }
 
 // Unleak will release the reference to the killerPool
@@ -36,20 +58,129 @@ func (tx *Tx) Unleak() {
 	tx.killerPool = nil
 	tx.connectionID = ""
 	tx.kto = 0
+	tx.tracked.markUnleaked()
+}
+
+// OnCommit registers fn to be called after the transaction has been
+// successfully committed. Callbacks are called in the order they were registered.
+// They are not called if the commit fails.
+func (tx *Tx) OnCommit(fn func()) {
+	tx.lock.Lock()
+	tx.commitHooks = append(tx.commitHooks, fn)
+	tx.lock.Unlock()
+}
+
+// OnRollback registers fn to be called after the transaction has been rolled back,
+// or after the commit has failed and the transaction is known to have been rolled back.
+// fn is passed the error returned by Rollback or Commit.
+// Callbacks are called in the order they were registered.
+func (tx *Tx) OnRollback(fn func(err error)) {
+	tx.lock.Lock()
+	tx.rollbackHooks = append(tx.rollbackHooks, fn)
+	tx.lock.Unlock()
+}
+
+// OnUnknown registers fn to be called if the commit fails in a way that does not
+// reveal whether the transaction was committed. For example: the connection was
+// lost after the COMMIT was sent. fn is passed the error returned by Commit.
+// Callbacks are called in the order they were registered.
+func (tx *Tx) OnUnknown(fn func(err error)) {
+	tx.lock.Lock()
+	tx.unknownHooks = append(tx.unknownHooks, fn)
+	tx.lock.Unlock()
+}
+
+// txOutcome is the outcome of a transaction that determines which callbacks are called.
+type txOutcome int
+
+const (
+	txCommitted txOutcome = iota
+	txRolledBack
+	txUnknown
+)
+
+// commitOutcome determines the outcome of a transaction from the error returned by COMMIT.
+func commitOutcome(err error) txOutcome {
+	if err == nil {
+		return txCommitted
+	}
+
+	// The transaction was rolled back before the COMMIT was sent, or the
+	// server rejected the COMMIT.
+	if errors.Is(err, ErrTxTimeout) || errors.Is(err, stdSql.ErrTxDone) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return txRolledBack
+	}
+	if _, ok := mysqlErrNumber(err); ok {
+		return txRolledBack
+	}
+	return txUnknown
+}
+
+// runHooks calls the callbacks registered for the outcome of the transaction.
+// Callbacks are only ever called once.
+func (tx *Tx) runHooks(outcome txOutcome, err error) {
+	tx.lock.Lock()
+	commitHooks, rollbackHooks, unknownHooks := tx.commitHooks, tx.rollbackHooks, tx.unknownHooks
+	tx.commitHooks, tx.rollbackHooks, tx.unknownHooks = nil, nil, nil
+	tx.lock.Unlock()
+
+	switch outcome {
+	case txCommitted:
+		for _, fn := range commitHooks {
+			fn()
+		}
+	case txRolledBack:
+		for _, fn := range rollbackHooks {
+			fn(err)
+		}
+	default:
+		for _, fn := range unknownHooks {
+			fn(err)
+		}
+	}
 }
 
 // Commit commits the transaction.
-func (tx *Tx) Commit() error {
+//
+// Callbacks registered using OnCommit are called after the transaction has
+// been committed. If the commit fails, callbacks registered using OnRollback
+// are called if the transaction is known to have been rolled back (for example,
+// MySQL returned an error). Otherwise, callbacks registered using OnUnknown are
+// called instead (for example, the connection was lost).
+func (tx *Tx) Commit() (err error) {
+	atomic.StoreInt32(&tx.closed, 1)
+	tx.tracked.markClosed()
+
+	defer func() {
+		tx.runHooks(commitOutcome(err), err)
+	}()
+
 	defer func() {
 		// Perhaps only do this if err == nil
 		tx.lock.Lock()
 		for i := range tx.stmts {
+			atomic.StoreInt32(&tx.stmts[i].closed, 1) // Closed by the sql package
+			tx.stmts[i].tracked.markClosed()
 			tx.stmts[i].Unleak()
 		}
 		tx.lock.Unlock()
 	}()
 
-	err := tx.tx.Commit()
+	if tx.monitored != nil {
+		tx.monitored.untrack()
+	}
+
+	if tx.finish() {
+		// The connection has been killed so the transaction was rolled back
+		tx.tx.Rollback()
+		err = ErrTxTimeout
+	} else {
+		err = tx.run(context.Background(), OpCommit, func(ctx context.Context) error {
+			return tx.tx.Commit()
+		})
+		tx.db.noteErr(err)
+	}
 	// if err == nil { See: https://github.com/golang/go/issues/28474
 	tx.Unleak()
 	// }
@@ -59,51 +190,20 @@ func (tx *Tx) Commit() error {
 // Exec executes a query that doesn't return rows.
 // For example: an INSERT and UPDATE.
 func (tx *Tx) Exec(query string, args ...interface{}) (stdSql.Result, error) {
-	return tx.tx.Exec(query, args...)
+	return tx.ExecContext(context.Background(), query, args...)
 }
 
 // ExecContext executes a query that doesn't return rows.
 // For example: an INSERT and UPDATE.
 func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (stdSql.Result, error) {
 
-	// Create a context that is used to cancel ExecContext()
-	cancelCtx, cancelFunc := context.WithCancel(context.Background())
-	defer cancelFunc()
-
-	outChan := make(chan stdSql.Result)
-	errChan := make(chan error)
-	returnedChan := make(chan struct{}) // Used to indicate that this function has returned
-
-	defer func() {
-		returnedChan <- struct{}{}
-	}()
-
-	go func() {
-		select {
-		case <-ctx.Done():
-			// context has been canceled
-			kill(tx.killerPool, tx.connectionID, tx.kto)
-			errChan <- ctx.Err()
-		case <-returnedChan:
-		}
-	}()
-
-	go func() {
-		res, err := tx.tx.ExecContext(cancelCtx, query, args...)
-		if err != nil {
-			errChan <- err
-			return
-		}
-		outChan <- res
-	}()
-
-	select {
-	case err := <-errChan:
-		return nil, err
-	case out := <-outChan:
-		return out, nil
+	if tx.monitored != nil {
+		tx.monitored.statement(query)
 	}
 
+	return tx.runExec(ctx, &Call{Query: query, Args: args}, func(ctx context.Context, call *Call) (stdSql.Result, error) {
+		return tx.tx.ExecContext(ctx, call.Query, call.Args...)
+	})
 }
 
 // Prepare creates a prepared statement for use within a transaction.
@@ -127,13 +227,13 @@ func (tx *Tx) Prepare(query string) (*Stmt, error) {
 // for the execution of the returned statement. The returned statement
 // will run in the transaction context.
 func (tx *Tx) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
-	// You can not cancel a Prepare.
-	// See: https://github.com/rocketlaunchr/mysql-go/issues/3
-	stmt, err := tx.tx.PrepareContext(ctx, query)
+	st, err := tx.runPrepare(ctx, &Call{Query: query}, func(ctx context.Context, call *Call) (*stdSql.Stmt, error) {
+		return tx.tx.PrepareContext(ctx, call.Query)
+	})
 	if err != nil {
 		return nil, err
 	}
-	st := &Stmt{stmt, tx.killerPool, tx.connectionID, tx.kto}
+	st.monitored = tx.monitored
 	tx.lock.Lock()
 	tx.stmts = append(tx.stmts, st)
 	tx.lock.Unlock()
@@ -148,16 +248,13 @@ func (tx *Tx) Query(query string, args ...interface{}) (*Rows, error) {
 // QueryContext executes a query that returns rows, typically a SELECT.
 func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
 
-	// We can't use the same approach used in ExecContext because defer cancelFunc()
-	// cancels rows.Scan.
-	defer func() {
-		if ctx.Err() != nil {
-			kill(tx.killerPool, tx.connectionID, tx.kto)
-		}
-	}()
+	if tx.monitored != nil {
+		tx.monitored.statement(query)
+	}
 
-	rows, err := tx.tx.QueryContext(ctx, query, args...)
-	return &Rows{ctx: ctx, rows: rows, killerPool: tx.killerPool, connectionID: tx.connectionID}, err
+	return tx.runQuery(ctx, &Call{Query: query, Args: args}, func(ctx context.Context, call *Call) (*stdSql.Rows, error) {
+		return tx.tx.QueryContext(ctx, call.Query, call.Args...)
+	})
 }
 
 // QueryRow executes a query that is expected to return at most one row.
@@ -178,29 +275,49 @@ func (tx *Tx) QueryRow(query string, args ...interface{}) *Row {
 // the rest.
 func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
 
-	// Since sql.Row does not export err field, this is the best we can do:
-	defer func() {
-		if ctx.Err() != nil {
-			kill(tx.killerPool, tx.connectionID, tx.kto)
-		}
-	}()
+	if tx.monitored != nil {
+		tx.monitored.statement(query)
+	}
 
-	row := tx.tx.QueryRowContext(ctx, query, args...)
-	return &Row{ctx: ctx, row: row, killerPool: tx.killerPool, connectionID: tx.connectionID}
+	return tx.runQueryRow(ctx, &Call{Query: query, Args: args}, func(ctx context.Context, call *Call) (*stdSql.Rows, error) {
+		return tx.tx.QueryContext(ctx, call.Query, call.Args...)
+	})
 }
 
 // Rollback aborts the transaction.
-func (tx *Tx) Rollback() error {
+//
+// Callbacks registered using OnRollback are called after the transaction
+// has been rolled back.
+func (tx *Tx) Rollback() (err error) {
+	atomic.StoreInt32(&tx.closed, 1)
+	tx.tracked.markClosed()
+
+	defer func() {
+		tx.runHooks(txRolledBack, err)
+	}()
+
 	defer func() {
 		// Perhaps only do this if err == nil
 		tx.lock.Lock()
 		for i := range tx.stmts {
+			atomic.StoreInt32(&tx.stmts[i].closed, 1) // Closed by the sql package
+			tx.stmts[i].tracked.markClosed()
 			tx.stmts[i].Unleak()
 		}
 		tx.lock.Unlock()
 	}()
 
-	err := tx.tx.Rollback()
+	if tx.monitored != nil {
+		tx.monitored.untrack()
+	}
+
+	timedOut := tx.finish()
+	err = tx.run(context.Background(), OpRollback, func(ctx context.Context) error {
+		return tx.tx.Rollback()
+	})
+	if timedOut {
+		err = ErrTxTimeout
+	}
 	// if err == nil { // See: https://github.com/golang/go/issues/28474
 	tx.Unleak()
 	// }
@@ -240,7 +357,11 @@ func (tx *Tx) Stmt(stmt *stdSql.Stmt) *Stmt {
 // when the transaction has been committed or rolled back.
 func (tx *Tx) StmtContext(ctx context.Context, stmt *stdSql.Stmt) *Stmt {
 
-	st := &Stmt{tx.tx.StmtContext(ctx, stmt), tx.killerPool, tx.connectionID, tx.kto}
+	st := &Stmt{session: tx.session, stmt: tx.tx.StmtContext(ctx, stmt), monitored: tx.monitored}
+	if tx.db != nil && tx.db.Logger != nil {
+		runtime.SetFinalizer(st, (*Stmt).leaked)
+	}
+	tx.db.trackStmt(st)
 	tx.lock.Lock()
 	tx.stmts = append(tx.stmts, st)
 	tx.lock.Unlock()
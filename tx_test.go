@@ -0,0 +1,134 @@
+package sql_test
+
+import (
+	"context"
+	stdSql "database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+// hooks records the callbacks called for a transaction.
+type hooks struct {
+	committed  int
+	rolledBack []error
+	unknown    []error
+}
+
+func (h *hooks) register(tx *sql.Tx) {
+	tx.OnCommit(func() { h.committed++ })
+	tx.OnRollback(func(err error) { h.rolledBack = append(h.rolledBack, err) })
+	tx.OnUnknown(func(err error) { h.unknown = append(h.unknown, err) })
+}
+
+func beginTx(t *testing.T, pool *sql.DB) *sql.Tx {
+	t.Helper()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	tx, err := conn.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	return tx
+}
+
+func TestTxHooksCommit(t *testing.T) {
+	pool := sqltest.NewRecorder().DB()
+	defer pool.Close()
+
+	var h hooks
+	tx := beginTx(t, pool)
+	h.register(tx)
+
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, 1, h.committed)
+	assert.Empty(t, h.rolledBack)
+	assert.Empty(t, h.unknown)
+
+	// Callbacks are only called once
+	assert.Error(t, tx.Commit())
+	assert.Equal(t, 1, h.committed)
+}
+
+func TestTxHooksRollback(t *testing.T) {
+	pool := sqltest.NewRecorder().DB()
+	defer pool.Close()
+
+	var h hooks
+	tx := beginTx(t, pool)
+	h.register(tx)
+
+	require.NoError(t, tx.Rollback())
+	assert.Equal(t, 0, h.committed)
+	assert.Equal(t, []error{nil}, h.rolledBack)
+	assert.Empty(t, h.unknown)
+}
+
+func TestTxHooksCommitRejected(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`^COMMIT$`).WillReturnError(sqltest.ErrLockDeadlock)
+	pool := rec.DB()
+	defer pool.Close()
+
+	var h hooks
+	tx := beginTx(t, pool)
+	h.register(tx)
+
+	// MySQL rejected the COMMIT, so the transaction was rolled back
+	err := tx.Commit()
+	assert.Equal(t, sqltest.ErrLockDeadlock, err)
+	assert.Equal(t, 0, h.committed)
+	assert.Equal(t, []error{err}, h.rolledBack)
+	assert.Empty(t, h.unknown)
+}
+
+func TestTxHooksCommitUnknown(t *testing.T) {
+	fc := &sqltest.FaultConnector{
+		Connector: sqltest.NewRecorder(),
+		Faults:    []sqltest.Fault{{Query: regexp.MustCompile(`^COMMIT$`), Always: true, Drop: true}},
+	}
+	pool := &sql.DB{DB: stdSql.OpenDB(fc)}
+	defer pool.Close()
+
+	var h hooks
+	tx := beginTx(t, pool)
+	h.register(tx)
+
+	// The connection was lost, so the COMMIT may or may not have been applied
+	err := tx.Commit()
+	assert.Equal(t, mysql.ErrInvalidConn, err)
+	assert.Equal(t, 0, h.committed)
+	assert.Empty(t, h.rolledBack)
+	assert.Equal(t, []error{err}, h.unknown)
+}
+
+func TestTxHooksContextCanceled(t *testing.T) {
+	pool := sqltest.NewRecorder().DB()
+	defer pool.Close()
+
+	conn, err := pool.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err := conn.BeginTx(ctx, nil)
+	require.NoError(t, err)
+
+	var h hooks
+	h.register(tx)
+
+	// database/sql rolls the transaction back when its context is canceled
+	cancel()
+	assert.Error(t, tx.Commit())
+	assert.Equal(t, 0, h.committed)
+	assert.Len(t, h.rolledBack, 1)
+	assert.Empty(t, h.unknown)
+}
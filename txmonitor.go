@@ -0,0 +1,216 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	stdSql "database/sql"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// TxInfo describes a long running transaction reported by TxMonitor.
+type TxInfo struct {
+	ConnectionID  string
+	Started       time.Time
+	Duration      time.Duration
+	Stack         []byte // Stack of the goroutine that started the transaction (see CaptureStack)
+	LastStatement string
+
+	// The fields below are only populated if TxMonitor.InnoDBStats is set.
+	InnoDBState  string // trx_state
+	RowsLocked   int64  // trx_rows_locked
+	RowsModified int64  // trx_rows_modified (approximates the size of the undo log)
+}
+
+// TxMonitor tracks every open transaction started using Conn.BeginTx and reports
+// transactions that remain open for longer than a threshold.
+// Long running (and often idle) transactions hold locks and
+// prevent purging, which causes replication lag.
+//
+// Start must be called to begin monitoring.
+type TxMonitor struct {
+
+	// Threshold is how long a transaction can remain open before it is reported.
+	Threshold time.Duration
+
+	// Interval sets how often open transactions are checked.
+	// A value of zero defaults to 1 second.
+	Interval time.Duration
+
+	// Report is called once for each transaction that exceeds Threshold.
+	Report func(info TxInfo)
+
+	// InnoDBStats sets whether information_schema.INNODB_TRX is queried
+	// (using Pool) to report the rows locked and modified by the transaction.
+	InnoDBStats bool
+
+	// Pool is used to query information_schema.INNODB_TRX. It should be a dedicated
+	// diagnostics pool connected to the same server as the transactions. If not
+	// provided, the KillerPool is used, which may delay KILL signals.
+	Pool StdSQLDB
+
+	// CaptureStack sets whether the stack of the goroutine that started each
+	// transaction is captured and reported in TxInfo.Stack. Capturing the stack
+	// is costly, so it is disabled by default.
+	CaptureStack bool
+
+	lock   sync.Mutex
+	txs    map[uint64]*txRecord
+	nextID uint64
+	stop   chan struct{}
+}
+
+// txRecord stores the details of an open transaction.
+// It does not refer to the Tx so that a leaked Tx can be garbage collected.
+type txRecord struct {
+	monitor      *TxMonitor
+	id           uint64
+	killerPool   StdSQLDB
+	connectionID string
+	started      time.Time
+	stack        []byte
+	reported     bool
+
+	lock          sync.Mutex
+	lastStatement string
+}
+
+// Start begins monitoring open transactions in the background.
+// Calling Start on a TxMonitor that has already started does nothing.
+func (m *TxMonitor) Start() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+
+	interval := m.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				ctx, cancelFunc := context.WithTimeout(context.Background(), interval)
+				m.Check(ctx)
+				cancelFunc()
+			}
+		}
+	}(m.stop)
+}
+
+// Stop ends the monitoring of open transactions.
+func (m *TxMonitor) Stop() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// Check reports every open transaction that has exceeded Threshold and has
+// not already been reported. It is called periodically after Start.
+func (m *TxMonitor) Check(ctx context.Context) {
+
+	now := time.Now()
+	var long []*txRecord
+
+	m.lock.Lock()
+	for _, rec := range m.txs {
+		if !rec.reported && now.Sub(rec.started) > m.Threshold {
+			rec.reported = true
+			long = append(long, rec)
+		}
+	}
+	m.lock.Unlock()
+
+	for _, rec := range long {
+		rec.lock.Lock()
+		info := TxInfo{
+			ConnectionID:  rec.connectionID,
+			Started:       rec.started,
+			Duration:      now.Sub(rec.started),
+			Stack:         rec.stack,
+			LastStatement: rec.lastStatement,
+		}
+		rec.lock.Unlock()
+
+		if m.InnoDBStats {
+			pool := m.Pool
+			if pool == nil {
+				pool = rec.killerPool
+			}
+
+			var state stdSql.NullString
+			var locked, modified stdSql.NullInt64
+			err := pool.QueryRowContext(ctx, "SELECT trx_state, trx_rows_locked, trx_rows_modified FROM information_schema.INNODB_TRX WHERE trx_mysql_thread_id = ?", rec.connectionID).Scan(&state, &locked, &modified)
+			if err == nil {
+				info.InnoDBState, info.RowsLocked, info.RowsModified = state.String, locked.Int64, modified.Int64
+			}
+		}
+
+		if m.Report != nil {
+			m.Report(info)
+		}
+	}
+}
+
+// track starts tracking an open transaction.
+func (m *TxMonitor) track(tx *Tx) *txRecord {
+	rec := &txRecord{
+		monitor:      m,
+		killerPool:   tx.killerPool,
+		connectionID: tx.connectionID,
+		started:      time.Now(),
+	}
+	if m.CaptureStack {
+		rec.stack = debug.Stack()
+	}
+
+	m.lock.Lock()
+	if m.txs == nil {
+		m.txs = map[uint64]*txRecord{}
+	}
+	m.nextID++
+	rec.id = m.nextID
+	m.txs[rec.id] = rec
+	m.lock.Unlock()
+	return rec
+}
+
+// untrack stops tracking a transaction that has been committed, rolled back
+// or garbage collected.
+func (rec *txRecord) untrack() {
+	m := rec.monitor
+	m.lock.Lock()
+	delete(m.txs, rec.id)
+	m.lock.Unlock()
+}
+
+// statement records the last statement run by the transaction.
+func (rec *txRecord) statement(query string) {
+	rec.lock.Lock()
+	rec.lastStatement = query
+	rec.lock.Unlock()
+}
+
+// txMonitor returns the TxMonitor of the DB (if any).
+func (db *DB) txMonitor() *TxMonitor {
+	if db == nil {
+		return nil
+	}
+	return db.TxMonitor
+}
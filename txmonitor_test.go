@@ -0,0 +1,140 @@
+package sql_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+func TestTxMonitor(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	rec.Expect(`INNODB_TRX`).WillReturnRows([]string{"trx_state", "trx_rows_locked", "trx_rows_modified"}, []interface{}{"RUNNING", int64(3), int64(2)})
+
+	var reported []sql.TxInfo
+	pool := rec.DB()
+	pool.TxMonitor = &sql.TxMonitor{
+		Threshold:   10 * time.Millisecond,
+		Report:      func(info sql.TxInfo) { reported = append(reported, info) },
+		InnoDBStats: true,
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+
+	// Committed before exceeding Threshold
+	tx := beginTx(t, pool)
+	require.NoError(t, tx.Commit())
+
+	tx, connectionID := beginTxWithOptions(t, pool, nil)
+	_, err := tx.ExecContext(ctx, "UPDATE users SET name = 'alice'")
+	require.NoError(t, err)
+
+	// Statements prepared for the transaction are recorded
+	stmt, err := tx.PrepareContext(ctx, "UPDATE orders SET status = ?")
+	require.NoError(t, err)
+	_, err = stmt.ExecContext(ctx, "paid")
+	require.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+	pool.TxMonitor.Check(ctx)
+
+	if assert.Len(t, reported, 1) {
+		info := reported[0]
+		assert.Equal(t, connectionID, info.ConnectionID)
+		assert.Equal(t, "UPDATE orders SET status = ?", info.LastStatement)
+		assert.True(t, info.Duration >= 20*time.Millisecond)
+		assert.Nil(t, info.Stack)
+		assert.Equal(t, "RUNNING", info.InnoDBState)
+		assert.Equal(t, int64(3), info.RowsLocked)
+		assert.Equal(t, int64(2), info.RowsModified)
+	}
+
+	// A transaction is only reported once
+	pool.TxMonitor.Check(ctx)
+	assert.Len(t, reported, 1)
+	require.NoError(t, tx.Rollback())
+}
+
+func TestTxMonitorPool(t *testing.T) {
+	rec := sqltest.NewRecorder()
+
+	diag := sqltest.NewRecorder()
+	diag.Expect(`INNODB_TRX`).WillReturnRows([]string{"trx_state", "trx_rows_locked", "trx_rows_modified"}, []interface{}{"LOCK WAIT", int64(1), int64(0)})
+	diagPool := diag.DB()
+	defer diagPool.Close()
+
+	var reported []sql.TxInfo
+	pool := rec.DB()
+	pool.TxMonitor = &sql.TxMonitor{
+		Report:      func(info sql.TxInfo) { reported = append(reported, info) },
+		InnoDBStats: true,
+		Pool:        diagPool.DB,
+	}
+	defer pool.Close()
+
+	tx := beginTx(t, pool)
+	pool.TxMonitor.Check(context.Background())
+	require.NoError(t, tx.Rollback())
+
+	if assert.Len(t, reported, 1) {
+		assert.Equal(t, "LOCK WAIT", reported[0].InnoDBState)
+	}
+
+	// INNODB_TRX is not queried using the KillerPool
+	for _, stmt := range rec.Statements() {
+		assert.NotContains(t, stmt.Query, "INNODB_TRX")
+	}
+}
+
+func TestTxMonitorCaptureStack(t *testing.T) {
+	var reported []sql.TxInfo
+	pool := sqltest.NewRecorder().DB()
+	pool.TxMonitor = &sql.TxMonitor{
+		Report:       func(info sql.TxInfo) { reported = append(reported, info) },
+		CaptureStack: true,
+	}
+	defer pool.Close()
+
+	tx := beginTx(t, pool)
+	defer tx.Rollback()
+
+	time.Sleep(time.Millisecond)
+	pool.TxMonitor.Check(context.Background())
+
+	if assert.Len(t, reported, 1) {
+		assert.Contains(t, string(reported[0].Stack), "TestTxMonitorCaptureStack")
+	}
+}
+
+func TestTxMonitorLeaked(t *testing.T) {
+	var reported []sql.TxInfo
+	pool := sqltest.NewRecorder().DB()
+	pool.TxMonitor = &sql.TxMonitor{
+		Report: func(info sql.TxInfo) { reported = append(reported, info) },
+	}
+	defer pool.Close()
+
+	// The Tx (and its Conn) are never closed
+	func() {
+		conn, err := pool.Conn(context.Background())
+		require.NoError(t, err)
+		_, err = conn.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+	}()
+
+	// The monitor does not prevent the Tx from being garbage collected
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	pool.TxMonitor.Check(context.Background())
+	assert.Empty(t, reported)
+}
@@ -0,0 +1,85 @@
+// Copyright 2018-19 PJ Engineering and Business Solutions Pty. Ltd. All rights reserved.
+
+package sql
+
+import (
+	"context"
+	stdSql "database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTxTimeout is returned by Commit and Rollback when the transaction
+// exceeded TxOptions.MaxDuration. The transaction has been rolled back.
+var ErrTxTimeout = errors.New("sql: transaction exceeded max duration")
+
+// Possible values of Tx.state
+const (
+	txActive int32 = iota
+	txTimedOut
+	txFinished
+)
+
+// TxOptions holds the transaction options to be used in Conn.BeginTxWithOptions.
+type TxOptions struct {
+
+	// Isolation is the transaction isolation level.
+	// If zero, the driver or database's default level is used.
+	Isolation stdSql.IsolationLevel
+
+	// ReadOnly sets the transaction to be read-only.
+	ReadOnly bool
+
+	// MaxDuration sets the maximum duration of the transaction.
+	// After MaxDuration, the connection is killed (not just the running query) so that
+	// the server rolls back the transaction and releases its locks, even if the
+	// application is stuck and never calls Commit or Rollback.
+	//
+	// A value of zero is equivalent to no time limit.
+	MaxDuration time.Duration
+}
+
+// BeginTxWithOptions starts a transaction using the provided options.
+// See BeginTx for details.
+//
+// A KILL QUERY signal (sent when the context is canceled) leaves the transaction
+// open and holding locks until the application rolls back. If TxOptions.MaxDuration
+// is set, the connection is killed once the transaction exceeds it.
+// Commit and Rollback then return ErrTxTimeout, and the Conn can no longer be used.
+func (c *Conn) BeginTxWithOptions(ctx context.Context, opts *TxOptions) (*Tx, error) {
+
+	if opts == nil {
+		return c.BeginTx(ctx, nil)
+	}
+
+	tx, err := c.BeginTx(ctx, &stdSql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxDuration > 0 {
+		// Retained because Commit and Rollback unleak the Tx
+		db, killerPool, connectionID, kto := tx.db, tx.killerPool, tx.connectionID, tx.kto
+
+		tx.timer = time.AfterFunc(opts.MaxDuration, func() {
+			if atomic.CompareAndSwapInt32(&tx.state, txActive, txTimedOut) {
+				db.killConnection(context.Background(), killerPool, connectionID, kto)
+			}
+		})
+	}
+
+	return tx, nil
+}
+
+// finish marks the transaction as finished and stops the MaxDuration timer.
+// It reports whether the transaction had already exceeded its max duration.
+func (tx *Tx) finish() bool {
+	if tx.timer != nil {
+		tx.timer.Stop()
+	}
+	if atomic.CompareAndSwapInt32(&tx.state, txActive, txFinished) {
+		return false
+	}
+	return atomic.LoadInt32(&tx.state) == txTimedOut
+}
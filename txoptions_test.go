@@ -0,0 +1,100 @@
+package sql_test
+
+import (
+	"bytes"
+	"context"
+	stdSql "database/sql"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sql "github.com/rocketlaunchr/mysql-go"
+	"github.com/rocketlaunchr/mysql-go/sqltest"
+)
+
+// syncBuffer is a bytes.Buffer that can be written to by the MaxDuration timer
+// while it is read by the test.
+type syncBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+func beginTxWithOptions(t *testing.T, pool *sql.DB, opts *sql.TxOptions) (*sql.Tx, string) {
+	t.Helper()
+
+	ctx := context.Background()
+	conn, err := pool.Conn(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	var connectionID string
+	require.NoError(t, conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&connectionID))
+
+	tx, err := conn.BeginTxWithOptions(ctx, opts)
+	require.NoError(t, err)
+	return tx, connectionID
+}
+
+func TestTxMaxDuration(t *testing.T) {
+	rec := sqltest.NewRecorder()
+
+	var buf syncBuffer
+	pool := rec.DB()
+	pool.Logger = sql.NewSlogLogger(slog.NewTextHandler(&buf, nil))
+	defer pool.Close()
+
+	// Finished in time
+	tx, connectionID := beginTxWithOptions(t, pool, &sql.TxOptions{MaxDuration: time.Minute})
+	require.NoError(t, tx.Commit())
+	assert.False(t, rec.Killed(connectionID))
+
+	// Exceeded MaxDuration
+	tx, connectionID = beginTxWithOptions(t, pool, &sql.TxOptions{MaxDuration: 10 * time.Millisecond})
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, sql.ErrTxTimeout, tx.Commit())
+	assert.Equal(t, sql.ErrTxTimeout, tx.Rollback())
+	assert.True(t, rec.Killed(connectionID))
+
+	assert.Eventually(t, func() bool { return strings.Contains(buf.String(), `msg="connection killed"`) }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, uint64(1), pool.CancelStats().KillsSucceeded)
+}
+
+func TestTxMaxDurationKillFailed(t *testing.T) {
+	rec := sqltest.NewRecorder()
+	killer := &sqltest.FaultConnector{
+		Connector: rec,
+		Faults:    []sqltest.Fault{{Query: regexp.MustCompile(`^KILL`), Always: true, Err: errors.New("access denied")}},
+	}
+	pool := &sql.DB{DB: stdSql.OpenDB(rec), KillerPool: stdSql.OpenDB(killer)}
+	defer pool.Close()
+
+	var buf syncBuffer
+	pool.Logger = sql.NewSlogLogger(slog.NewTextHandler(&buf, nil))
+
+	tx, _ := beginTxWithOptions(t, pool, &sql.TxOptions{MaxDuration: 10 * time.Millisecond})
+	assert.Eventually(t, func() bool { return strings.Contains(buf.String(), `msg="kill failed"`) }, time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, sql.ErrTxTimeout, tx.Commit())
+	assert.Equal(t, uint64(1), pool.CancelStats().KillsFailed)
+	assert.Contains(t, buf.String(), `error="access denied"`)
+}